@@ -3,6 +3,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -21,24 +23,347 @@ type DatabaseConfig struct {
 	ConnectBackoff time.Duration
 }
 
+const (
+	// ErrorFormatProblem renders REST API errors as RFC 7807 problem details.
+	ErrorFormatProblem = "problem"
+
+	// ErrorFormatLegacy renders REST API errors as an ErrorResponse with a single error_message.
+	ErrorFormatLegacy = "legacy"
+)
+
+const (
+	// ClientAuthNone doesn't request client certificates.
+	ClientAuthNone = "none"
+
+	// ClientAuthOptional verifies client certificates when the client sends one.
+	ClientAuthOptional = "optional"
+
+	// ClientAuthRequire requires clients to send a verified certificate.
+	ClientAuthRequire = "require"
+)
+
+// TLSConfig defines the TLS configuration of a server; TLS is disabled when no certificate is set.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// CAFile holds the PEM encoded CAs used to verify client certificates.
+	CAFile     string
+	ClientAuth string
+	// AllowedSubjects restricts client certificates to those with a matching subject common name
+	// or distinguished name; any verified client is allowed if both allowlists are empty.
+	AllowedSubjects []string
+	// AllowedSANs restricts client certificates to those with a matching DNS, email, IP or URI SAN.
+	AllowedSANs []string
+	// ReloadInterval is how often the certificate files are checked for changes; 0 disables reload.
+	ReloadInterval time.Duration
+	// MinVersion is the minimum TLS version accepted, e.g. 1.2.
+	MinVersion string
+	// CipherSuites restricts the TLS 1.0-1.2 cipher suites by name, e.g.
+	// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256; TLS 1.3 suites aren't configurable.
+	CipherSuites []string
+}
+
+// Enabled returns true if TLS is configured.
+func (conf *TLSConfig) Enabled() bool {
+	return conf.CertFile != ""
+}
+
 // HTTPConfig defines configuration specific to the REST API HTTP server.
 type HTTPConfig struct {
-	Address string
+	Address     string
+	ErrorFormat string
+	TLS         TLSConfig
+	// RedirectAddress is the address of a plaintext listener redirecting to HTTPS when TLS is enabled.
+	RedirectAddress string
+	// Descriptors enables serving the FileDescriptorSet of the APIs.
+	Descriptors bool
+	// Admin enables the admin endpoints, e.g. to change the log level at runtime.
+	Admin bool
+}
+
+// VehicleConfig defines configuration for vehicle resources.
+type VehicleConfig struct {
+	// Colors is the vocabulary of allowed vehicle colors; any color is allowed if empty.
+	Colors []string
+}
+
+// TenantConfig defines configuration for isolating resources per tenant.
+type TenantConfig struct {
+	// Header is the request header, and GRPC metadata key, holding the tenant of requests; it's
+	// ignored for unauthenticated requests unless TrustHeader is set.
+	Header string
+	// TrustHeader uses the Header as the tenant of unauthenticated requests, e.g. behind a gateway
+	// authenticating callers and setting it; otherwise they use the default tenant.
+	TrustHeader bool
+	// RowLevelSecurity also isolates tenants using Postgres row level security policies.
+	RowLevelSecurity bool
+	// DefaultPrincipals puts authenticated principals without a tenant in the default tenant rather
+	// than denying them, e.g. while migrating credentials issued before tenants.
+	DefaultPrincipals bool
 }
 
 // GrpcConfig defines configuration for the GRPC server.
 type GrpcConfig struct {
 	Address string
+	TLS     TLSConfig
+	// Reflection enables the GRPC server reflection service.
+	Reflection bool
+	// Admin enables the GRPC admin services, e.g. to change the log level at runtime.
+	Admin bool
+}
+
+// MuxConfig defines configuration for serving the REST API and GRPC on a single port.
+type MuxConfig struct {
+	// Address enables single port mode when set, replacing the HTTP and GRPC addresses.
+	Address string
+	// TLS is terminated by the single port listener for both REST and GRPC.
+	TLS TLSConfig
+}
+
+// Enabled returns true if single port mode is configured.
+func (conf *MuxConfig) Enabled() bool {
+	return conf.Address != ""
+}
+
+// Load loads the MuxConfig options from env vars overriding existing values.
+func (conf *MuxConfig) Load() {
+	conf.Address = GetEnv("MUX_ADDRESS", conf.Address)
+	conf.TLS.Load("MUX")
+}
+
+// MetricsConfig defines configuration for Prometheus metrics.
+type MetricsConfig struct {
+	Enabled bool
+	// Path of the metrics endpoint on the REST server.
+	Path string
+	// Namespace prefixes all metric names.
+	Namespace string
+	// HTTPBuckets are the REST request latency histogram buckets in seconds.
+	HTTPBuckets []float64
+	// GrpcBuckets are the GRPC request latency histogram buckets in seconds.
+	GrpcBuckets []float64
+	// QueryBuckets are the database query latency histogram buckets in seconds.
+	QueryBuckets []float64
+}
+
+// Load loads the MetricsConfig options from env vars overriding existing values.
+func (conf *MetricsConfig) Load() {
+	conf.Enabled = GetEnvBool("METRICS_ENABLED", conf.Enabled)
+	conf.Path = GetEnv("METRICS_PATH", conf.Path)
+	conf.Namespace = GetEnv("METRICS_NAMESPACE", conf.Namespace)
+	conf.HTTPBuckets = GetEnvFloats("METRICS_HTTP_BUCKETS", conf.HTTPBuckets)
+	conf.GrpcBuckets = GetEnvFloats("METRICS_GRPC_BUCKETS", conf.GrpcBuckets)
+	conf.QueryBuckets = GetEnvFloats("METRICS_QUERY_BUCKETS", conf.QueryBuckets)
+}
+
+const (
+	// TracingExporterNone disables tracing.
+	TracingExporterNone = "none"
+
+	// TracingExporterStdout writes spans as json to stdout.
+	TracingExporterStdout = "stdout"
+
+	// TracingExporterFile writes spans as json to the tracing file.
+	TracingExporterFile = "file"
+
+	// TracingExporterOTLP sends spans to an OpenTelemetry collector over GRPC.
+	TracingExporterOTLP = "otlp"
+)
+
+// TracingConfig defines configuration for OpenTelemetry tracing.
+type TracingConfig struct {
+	// Exporter is where spans are sent; one of the TracingExporter values.
+	Exporter    string
+	ServiceName string
+	// File is the path spans are appended to with the file exporter.
+	File string
+	// OTLPEndpoint is the host:port of the collector used by the otlp exporter.
+	OTLPEndpoint string
+	// OTLPInsecure disables TLS to the collector.
+	OTLPInsecure bool
+	// SampleRatio is the fraction of new traces sampled; traces propagated from callers follow the
+	// sampling decision of the caller.
+	SampleRatio float64
+}
+
+// Enabled returns true if an exporter is configured.
+func (conf *TracingConfig) Enabled() bool {
+	return conf.Exporter != "" && conf.Exporter != TracingExporterNone
+}
+
+// Load loads the TracingConfig options from env vars overriding existing values.
+func (conf *TracingConfig) Load() {
+	conf.Exporter = GetEnv("TRACING_EXPORTER", conf.Exporter)
+	conf.ServiceName = GetEnv("TRACING_SERVICE_NAME", conf.ServiceName)
+	conf.File = GetEnv("TRACING_FILE", conf.File)
+	conf.OTLPEndpoint = GetEnv("TRACING_OTLP_ENDPOINT", conf.OTLPEndpoint)
+	conf.OTLPInsecure = GetEnvBool("TRACING_OTLP_INSECURE", conf.OTLPInsecure)
+	conf.SampleRatio = GetEnvFloat("TRACING_SAMPLE_RATIO", conf.SampleRatio)
+}
+
+const (
+	// LogFormatJSON writes log entries as json.
+	LogFormatJSON = "json"
+
+	// LogFormatConsole writes human friendly log entries for development.
+	LogFormatConsole = "console"
+)
+
+// LogConfig defines configuration for logging.
+type LogConfig struct {
+	// Level is the default log level, e.g. info.
+	Level string
+	// Format is the output format; one of the LogFormat values.
+	Format string
+	// PackageLevels override the level of packages by name, e.g. resources=debug.
+	PackageLevels []string
+	// SamplingBurst is the number of high volume entries, e.g. access logs, written per sampling
+	// period before sampling kicks in; sampling is disabled if both burst and rate are 0.
+	SamplingBurst int
+	// SamplingPeriod is the period of the sampling burst.
+	SamplingPeriod time.Duration
+	// SamplingRate writes 1 in every SamplingRate entries past the burst; 0 drops them.
+	SamplingRate int
+	// RedactFields are redacted from log entries and logged queries, e.g. vehicle fields like vin,
+	// in addition to the sensitive keys that are always redacted, e.g. password.
+	RedactFields []string
+}
+
+// SamplingEnabled returns true if high volume entries are sampled.
+func (conf *LogConfig) SamplingEnabled() bool {
+	return conf.SamplingBurst > 0 || conf.SamplingRate > 1
+}
+
+// Load loads the LogConfig options from env vars overriding existing values.
+func (conf *LogConfig) Load() {
+	conf.Level = GetEnv("LOG_LEVEL", conf.Level)
+	conf.Format = GetEnv("LOG_FORMAT", conf.Format)
+	conf.PackageLevels = GetEnvList("LOG_PACKAGE_LEVELS", conf.PackageLevels)
+	conf.SamplingBurst = GetEnvInt("LOG_SAMPLING_BURST", conf.SamplingBurst)
+	conf.SamplingPeriod = GetEnvDuration("LOG_SAMPLING_PERIOD", conf.SamplingPeriod)
+	conf.SamplingRate = GetEnvInt("LOG_SAMPLING_RATE", conf.SamplingRate)
+	conf.RedactFields = GetEnvList("LOG_REDACT_FIELDS", conf.RedactFields)
+}
+
+// JWTConfig defines configuration for verifying JWT bearer tokens, e.g. issued by an OIDC provider.
+type JWTConfig struct {
+	// JWKSFile is the path of the JSON Web Key Set used to verify token signatures.
+	JWKSFile string
+	// JWKSURL is the URL of the JSON Web Key Set, e.g. the jwks_uri of the OIDC provider; the
+	// file takes precedence when both are set.
+	JWKSURL string
+	// Issuer is the required iss claim; the issuer isn't checked if empty.
+	Issuer string
+	// Audience are the accepted aud claims; the audience isn't checked if empty.
+	Audience []string
+	// CacheTTL is how long the JWKS is cached before being reloaded.
+	CacheTTL time.Duration
+	// Leeway is the clock skew allowed when checking the exp and nbf claims.
+	Leeway time.Duration
+	// RolesClaim is the claim holding the roles of the principal, either a string or a list.
+	RolesClaim string
+	// TenantClaim is the claim holding the tenant of the principal.
+	TenantClaim string
+}
+
+// Enabled returns true if JWT bearer tokens are verified.
+func (conf *JWTConfig) Enabled() bool {
+	return conf.JWKSFile != "" || conf.JWKSURL != ""
+}
+
+// Load loads the JWTConfig options from env vars overriding existing values.
+func (conf *JWTConfig) Load() {
+	conf.JWKSFile = GetEnv("AUTH_JWT_JWKS_FILE", conf.JWKSFile)
+	conf.JWKSURL = GetEnv("AUTH_JWT_JWKS_URL", conf.JWKSURL)
+	conf.Issuer = GetEnv("AUTH_JWT_ISSUER", conf.Issuer)
+	conf.Audience = GetEnvList("AUTH_JWT_AUDIENCE", conf.Audience)
+	conf.CacheTTL = GetEnvDuration("AUTH_JWT_CACHE_TTL", conf.CacheTTL)
+	conf.Leeway = GetEnvDuration("AUTH_JWT_LEEWAY", conf.Leeway)
+	conf.RolesClaim = GetEnv("AUTH_JWT_ROLES_CLAIM", conf.RolesClaim)
+	conf.TenantClaim = GetEnv("AUTH_JWT_TENANT_CLAIM", conf.TenantClaim)
+}
+
+// AuthConfig defines configuration for authenticating REST and GRPC requests.
+type AuthConfig struct {
+	// APIKeys accepts API keys as request credentials.
+	APIKeys bool
+	// JWT accepts JWT bearer tokens as request credentials when enabled.
+	JWT JWTConfig
+	// AdminKey is the key required by the admin endpoints, including those managing API keys.
+	AdminKey string
+	// UsageInterval is how often the last used time of an API key is updated at most.
+	UsageInterval time.Duration
+	// PolicyFile is the path of the authorization policy mapping roles to permissions; requests
+	// aren't authorized if empty.
+	PolicyFile string
+}
+
+// Enabled returns true if requests are authenticated, with API keys or JWT bearer tokens.
+func (conf *AuthConfig) Enabled() bool {
+	return conf.APIKeys || conf.JWT.Enabled()
+}
+
+// Load loads the AuthConfig options from env vars overriding existing values.
+func (conf *AuthConfig) Load() {
+	conf.APIKeys = GetEnvBool("AUTH_API_KEYS", conf.APIKeys)
+	conf.AdminKey = GetEnv("AUTH_ADMIN_KEY", conf.AdminKey)
+	conf.UsageInterval = GetEnvDuration("AUTH_KEY_USAGE_INTERVAL", conf.UsageInterval)
+	conf.PolicyFile = GetEnv("AUTH_POLICY_FILE", conf.PolicyFile)
+	conf.JWT.Load()
+}
+
+// HealthConfig defines configuration for health and readiness checks.
+type HealthConfig struct {
+	// CheckInterval is how often readiness is checked to update the GRPC health status.
+	CheckInterval time.Duration
+	// DrainDelay is how long servers keep serving once reported as not ready during shutdown, giving
+	// load balancers time to stop sending requests.
+	DrainDelay time.Duration
+}
+
+// Load loads the HealthConfig options from env vars overriding existing values.
+func (conf *HealthConfig) Load() {
+	conf.CheckInterval = GetEnvDuration("HEALTH_CHECK_INTERVAL", conf.CheckInterval)
+	conf.DrainDelay = GetEnvDuration("HEALTH_DRAIN_DELAY", conf.DrainDelay)
 }
 
 // Load loads the GrpcConfig options from env vars overriding existing values.
 func (conf *GrpcConfig) Load() {
 	conf.Address = GetEnv("GRPC_ADDRESS", conf.Address)
+	conf.TLS.Load("GRPC")
+	conf.Reflection = GetEnvBool("GRPC_REFLECTION", conf.Reflection)
+	conf.Admin = GetEnvBool("GRPC_ADMIN", conf.Admin)
+}
+
+// Load loads the TLSConfig options from env vars with the said prefix, e.g. GRPC_TLS_CERT_FILE,
+// falling back to the TLS_ env vars shared by all servers and then existing values.
+func (conf *TLSConfig) Load(prefix string) {
+	getEnv := func(key, defaultValue string) string {
+		return GetEnv(prefix+"_"+key, GetEnv(key, defaultValue))
+	}
+	getEnvList := func(key string, defaultValue []string) []string {
+		return GetEnvList(prefix+"_"+key, GetEnvList(key, defaultValue))
+	}
+	conf.CertFile = getEnv("TLS_CERT_FILE", conf.CertFile)
+	conf.KeyFile = getEnv("TLS_KEY_FILE", conf.KeyFile)
+	conf.CAFile = getEnv("TLS_CA_FILE", conf.CAFile)
+	conf.ClientAuth = getEnv("TLS_CLIENT_AUTH", conf.ClientAuth)
+	conf.AllowedSubjects = getEnvList("TLS_ALLOWED_SUBJECTS", conf.AllowedSubjects)
+	conf.AllowedSANs = getEnvList("TLS_ALLOWED_SANS", conf.AllowedSANs)
+	conf.MinVersion = getEnv("TLS_MIN_VERSION", conf.MinVersion)
+	conf.CipherSuites = getEnvList("TLS_CIPHER_SUITES", conf.CipherSuites)
+	conf.ReloadInterval = GetEnvDuration(prefix+"_TLS_RELOAD_INTERVAL",
+		GetEnvDuration("TLS_RELOAD_INTERVAL", conf.ReloadInterval))
 }
 
 // Load loads the HTTPConfig options from env vars overriding existing values.
 func (conf *HTTPConfig) Load() {
 	conf.Address = GetEnv("HTTP_ADDRESS", conf.Address)
+	conf.ErrorFormat = GetEnv("HTTP_ERROR_FORMAT", conf.ErrorFormat)
+	conf.TLS.Load("HTTP")
+	conf.RedirectAddress = GetEnv("HTTP_REDIRECT_ADDRESS", conf.RedirectAddress)
+	conf.Descriptors = GetEnvBool("HTTP_DESCRIPTORS", conf.Descriptors)
+	conf.Admin = GetEnvBool("HTTP_ADMIN", conf.Admin)
 	// TODO: expose timeouts in conf
 }
 
@@ -53,6 +378,105 @@ func (conf *DatabaseConfig) Load() {
 	conf.Timezone = GetEnv("DB_TIMEZONE", conf.Timezone)
 }
 
+// Load loads the VehicleConfig options from env vars overriding existing values.
+func (conf *VehicleConfig) Load() {
+	conf.Colors = GetEnvList("VEHICLE_COLORS", conf.Colors)
+}
+
+// Load loads the TenantConfig options from env vars overriding existing values.
+func (conf *TenantConfig) Load() {
+	conf.Header = GetEnv("TENANT_HEADER", conf.Header)
+	conf.TrustHeader = GetEnvBool("TENANT_TRUST_HEADER", conf.TrustHeader)
+	conf.RowLevelSecurity = GetEnvBool("TENANT_ROW_LEVEL_SECURITY", conf.RowLevelSecurity)
+	conf.DefaultPrincipals = GetEnvBool("TENANT_DEFAULT_PRINCIPALS", conf.DefaultPrincipals)
+}
+
+// GetEnvList gets the said comma separated env variable returning the defaultValue if not set.
+func GetEnvList(key string, defaultValue []string) []string {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// GetEnvFloats gets the said comma separated env variable as floats returning the defaultValue if
+// not set or invalid.
+func GetEnvFloats(key string, defaultValue []float64) []float64 {
+	if _, exists := os.LookupEnv(key); !exists {
+		return defaultValue
+	}
+	var values []float64
+	for _, v := range GetEnvList(key, nil) {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return defaultValue
+		}
+		values = append(values, f)
+	}
+	return values
+}
+
+// GetEnvFloat gets the said env variable as a float returning the defaultValue if not set or invalid.
+func GetEnvFloat(key string, defaultValue float64) float64 {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return f
+}
+
+// GetEnvInt gets the said env variable as an int returning the defaultValue if not set or invalid.
+func GetEnvInt(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return defaultValue
+	}
+	return i
+}
+
+// GetEnvBool gets the said env variable as a bool, e.g. true, returning the defaultValue if not set
+// or invalid.
+func GetEnvBool(key string, defaultValue bool) bool {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return defaultValue
+	}
+	return b
+}
+
+// GetEnvDuration gets the said env variable as a duration, e.g. 30s, returning the defaultValue if
+// not set or invalid.
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return defaultValue
+	}
+	return duration
+}
+
 // GetEnv gets the said env variable returning the defaultValue if not set.
 func GetEnv(key, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists {
@@ -2,6 +2,10 @@
 package db
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -10,7 +14,7 @@ import (
 	"github.com/bodenr/vehicle-api/config"
 	"github.com/bodenr/vehicle-api/log"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // singleton database instance
@@ -29,31 +33,67 @@ func isConnectionError(err error) bool {
 	return strings.Contains(err.Error(), "connection refused")
 }
 
+// transientErrorCodes are postgres error codes for failures that may succeed if retried.
+var transientErrorCodes = map[pq.ErrorCode]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"53300": true, // too_many_connections
+	"57P01": true, // admin_shutdown
+	"57P03": true, // cannot_connect_now
+}
+
+// IsTransientError checks if the given database error is transient in nature, such as a lost
+// connection, and the operation can be retried.
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || isConnectionError(err) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		// class 08 is connection exceptions
+		return pqErr.Code.Class() == "08" || transientErrorCodes[pqErr.Code]
+	}
+	return false
+}
+
 // connect tries to connect to the database using the said dsn.
-func connect(dsn string) (*sqlx.DB, error) {
-	db, err := sqlx.Connect("postgres", dsn)
+func connect(ctx context.Context, dsn string) (*sqlx.DB, error) {
+	db, err := sqlx.ConnectContext(ctx, "postgres", dsn)
 	if err != nil {
+		// NB: driver errors may include the dsn and so the password
+		err = log.RedactError(err)
 		log.Log.Err(err).Msg("error initializing database connection")
 		return nil, err
 	}
 	return db, nil
 }
 
-// connectRetry retries connecting with the said dsn in cases of connection error.
-func connectRetry(dsn string, retries int, delay time.Duration) (*sqlx.DB, error) {
+// connectRetry retries connecting with the said dsn in cases of connection error, giving up when the
+// context is done.
+func connectRetry(ctx context.Context, dsn string, retries int, delay time.Duration) (*sqlx.DB, error) {
 	for retry := 0; retry < retries; retry++ {
-		db, err := connect(dsn)
+		db, err := connect(ctx, dsn)
 		if err == nil {
 			return db, nil
+		} else if ctx.Err() != nil {
+			return nil, ctx.Err()
 		} else if isConnectionError(err) && delay > 0 {
-			time.Sleep(delay)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
 		}
 	}
 	return nil, fmt.Errorf("Database connection failed after %d attempts", retries)
 }
 
-// Initialize should be called to initialize the database connection prior to GetDB.
-func Initialize(conf *config.DatabaseConfig) error {
+// Initialize should be called to initialize the database connection prior to GetDB; connecting is
+// given up when the context is done.
+func Initialize(ctx context.Context, conf *config.DatabaseConfig) error {
 	lock.Lock()
 	defer lock.Unlock()
 
@@ -65,14 +105,14 @@ func Initialize(conf *config.DatabaseConfig) error {
 
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=%s",
 		conf.Host, conf.Username, conf.Password, conf.DatabaseName, conf.Port, conf.Timezone)
-	db, err := connect(dsn)
+	db, err := connect(ctx, dsn)
 
 	// TODO: better handling to wait for DB up
 	if err != nil && isConnectionError(err) && conf.ConnectRetries > 0 {
-		db, err = connectRetry(dsn, conf.ConnectRetries, conf.ConnectBackoff)
-		if err != nil {
-			return err
-		}
+		db, err = connectRetry(ctx, dsn, conf.ConnectRetries, conf.ConnectBackoff)
+	}
+	if err != nil {
+		return err
 	}
 
 	database = db
@@ -89,6 +129,33 @@ func GetDB() *sqlx.DB {
 	return database
 }
 
+// ErrNotInitialized is returned when the database is used before being created via Initialize.
+var ErrNotInitialized = errors.New("Database is not initialized")
+
+// Ping verifies the database connection is alive.
+func Ping(ctx context.Context) error {
+	lock.Lock()
+	db := database
+	lock.Unlock()
+
+	if db == nil {
+		return ErrNotInitialized
+	}
+	return db.PingContext(ctx)
+}
+
+// Stats returns the connection pool statistics of the database, which are zero if not initialized.
+func Stats() sql.DBStats {
+	lock.Lock()
+	db := database
+	lock.Unlock()
+
+	if db == nil {
+		return sql.DBStats{}
+	}
+	return db.Stats()
+}
+
 // Close closes the database connection and clears the singleton database reference.
 // This method is idempotent.
 func Close() error {
@@ -108,3 +175,26 @@ func Close() error {
 
 	return nil
 }
+
+// TenantSetting is the setting holding the tenant of the current transaction, used by row level
+// security policies to only expose the rows of the tenant.
+const TenantSetting = "app.tenant_id"
+
+// WithTenant runs fn in a transaction with TenantSetting set to the said tenant. The setting is
+// local to the transaction so it doesn't leak to other requests sharing the pooled connection.
+func WithTenant(ctx context.Context, tenant string, fn func(sqlx.ExtContext) error) error {
+	tx, err := GetDB().BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, "SELECT set_config($1, $2, true)", TenantSetting, tenant); err == nil {
+		err = fn(tx)
+	}
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.FromContext(ctx).Err(rbErr).Msg("error rolling back transaction")
+		}
+		return err
+	}
+	return tx.Commit()
+}
@@ -3,12 +3,27 @@ module github.com/bodenr/vehicle-api
 go 1.14
 
 require (
+	github.com/fxamacker/cbor/v2 v2.3.0
 	github.com/gogo/protobuf v1.3.1
-	github.com/golang/protobuf v1.4.2
+	github.com/golang/protobuf v1.4.3
 	github.com/gorilla/mux v1.8.0
 	github.com/jmoiron/sqlx v1.2.0
 	github.com/lib/pq v1.3.0
+	github.com/prometheus/client_golang v1.9.0
+	github.com/rs/xid v1.2.1
 	github.com/rs/zerolog v1.20.0
+	github.com/soheilhy/cmux v0.1.4
+	github.com/vmihailenco/msgpack/v5 v5.3.4
+	go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux v0.16.0
+	go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc v0.16.0
+	go.opentelemetry.io/otel v0.16.0
+	go.opentelemetry.io/otel/exporters/otlp v0.16.0
+	go.opentelemetry.io/otel/exporters/stdout v0.16.0
+	go.opentelemetry.io/otel/sdk v0.16.0
+	golang.org/x/net v0.0.0-20200625001655-4c5254603344
 	google.golang.org/appengine v1.6.7 // indirect
+	google.golang.org/genproto v0.0.0-20200526211855-cb27e3aa2013
 	google.golang.org/grpc v1.34.0
+	google.golang.org/protobuf v1.25.0
+	sigs.k8s.io/yaml v1.2.0
 )
@@ -0,0 +1,263 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bodenr/vehicle-api/log"
+)
+
+// minRefreshInterval is how often the keys are refreshed at most when a token has an unknown key ID,
+// so tokens with made up key IDs can't be used to flood the JWKS endpoint.
+const minRefreshInterval = 30 * time.Second
+
+// ErrKeysUnavailable is the error used when the JWKS can't be loaded.
+var ErrKeysUnavailable = errors.New("Token signing keys unavailable")
+
+// jsonWebKey is a JSON Web Key as per RFC 7517; only RSA and EC P-256 signing keys are supported.
+type jsonWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+}
+
+// publicKey is a parsed signing key along with the algorithm it verifies.
+type publicKey struct {
+	id        string
+	algorithm string
+	key       crypto.PublicKey
+}
+
+// decodeInt decodes a base64url encoded big-endian integer.
+func decodeInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// parse parses the JSON Web Key, returning nil for keys that aren't supported signing keys.
+func (jwk *jsonWebKey) parse() (*publicKey, error) {
+	if jwk.Use != "" && jwk.Use != "sig" {
+		return nil, nil
+	}
+	switch jwk.KeyType {
+	case "RSA":
+		if jwk.Algorithm != "" && jwk.Algorithm != AlgorithmRS256 {
+			return nil, nil
+		}
+		n, err := decodeInt(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid modulus of key %s: %v", jwk.KeyID, err)
+		}
+		e, err := decodeInt(jwk.E)
+		if err != nil || !e.IsInt64() {
+			return nil, fmt.Errorf("Invalid exponent of key %s", jwk.KeyID)
+		}
+		return &publicKey{
+			id:        jwk.KeyID,
+			algorithm: AlgorithmRS256,
+			key:       &rsa.PublicKey{N: n, E: int(e.Int64())},
+		}, nil
+	case "EC":
+		if jwk.Curve != "P-256" || (jwk.Algorithm != "" && jwk.Algorithm != AlgorithmES256) {
+			return nil, nil
+		}
+		x, err := decodeInt(jwk.X)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid x coordinate of key %s: %v", jwk.KeyID, err)
+		}
+		y, err := decodeInt(jwk.Y)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid y coordinate of key %s: %v", jwk.KeyID, err)
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, fmt.Errorf("Key %s isn't on the P-256 curve", jwk.KeyID)
+		}
+		return &publicKey{
+			id:        jwk.KeyID,
+			algorithm: AlgorithmES256,
+			key:       &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y},
+		}, nil
+	}
+	return nil, nil
+}
+
+// parseKeySet parses the supported signing keys of a JSON Web Key Set.
+func parseKeySet(data []byte) ([]*publicKey, error) {
+	set := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("Invalid JWKS: %v", err)
+	}
+	var keys []*publicKey
+	for i := range set.Keys {
+		key, err := set.Keys[i].parse()
+		if err != nil {
+			return nil, err
+		}
+		if key != nil {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS has no supported signing keys")
+	}
+	return keys, nil
+}
+
+// KeySet caches the signing keys of a JWKS file or URL, refreshing them once the cache TTL passes
+// or a token is signed with an unknown key, e.g. after the keys were rotated.
+type KeySet struct {
+	file   string
+	url    string
+	ttl    time.Duration
+	client *http.Client
+
+	lock        sync.Mutex
+	keys        []*publicKey
+	refreshedAt time.Time
+	// refreshing is closed once the refresh in flight, if any, is done.
+	refreshing chan struct{}
+}
+
+// NewKeySet creates a KeySet for the JWKS file, or else URL, caching keys for the said TTL.
+func NewKeySet(file, url string, ttl time.Duration) *KeySet {
+	return &KeySet{
+		file:   file,
+		url:    url,
+		ttl:    ttl,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// fetch reads the JWKS from the file or URL.
+func (set *KeySet) fetch(ctx context.Context) ([]byte, error) {
+	if set.file != "" {
+		return ioutil.ReadFile(set.file)
+	}
+	request, err := http.NewRequest(http.MethodGet, set.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := set.client.Do(request.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unexpected JWKS response status: %s", response.Status)
+	}
+	return ioutil.ReadAll(response.Body)
+}
+
+// load fetches and parses the keys.
+func (set *KeySet) load(ctx context.Context) ([]*publicKey, error) {
+	data, err := set.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return parseKeySet(data)
+}
+
+// Load loads the keys, failing if they can't be fetched or there are no supported signing keys.
+func (set *KeySet) Load(ctx context.Context) error {
+	keys, err := set.load(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to load JWKS: %v", err)
+	}
+	set.lock.Lock()
+	defer set.lock.Unlock()
+	set.keys, set.refreshedAt = keys, time.Now()
+	return nil
+}
+
+// reload reloads the keys, keeping the cached keys if it fails, then closes done. The keys are
+// fetched with their own context so a canceled request doesn't fail the refresh for the others.
+func (set *KeySet) reload(done chan struct{}) {
+	keys, err := set.load(context.Background())
+	if err != nil {
+		log.Log.Warn().Err(err).Msg("Failed to load JWKS")
+	}
+
+	set.lock.Lock()
+	defer set.lock.Unlock()
+	if err == nil {
+		set.keys = keys
+	}
+	// NB: failures also count as refreshed so an unavailable JWKS isn't fetched on every request
+	set.refreshedAt = time.Now()
+	set.refreshing = nil
+	close(done)
+}
+
+// refresh reloads the keys unless they were refreshed less than the said interval ago, waiting for
+// the reload until the context is done. Concurrent refreshes share a single reload.
+func (set *KeySet) refresh(ctx context.Context, interval time.Duration) {
+	set.lock.Lock()
+	if time.Since(set.refreshedAt) < interval {
+		set.lock.Unlock()
+		return
+	}
+	done := set.refreshing
+	if done == nil {
+		done = make(chan struct{})
+		set.refreshing = done
+		go set.reload(done)
+	}
+	set.lock.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+// find returns the keys of the said algorithm and key ID, or all keys of the algorithm if the
+// key ID is empty, along with whether any keys are loaded.
+func (set *KeySet) find(algorithm, keyID string) ([]*publicKey, bool) {
+	set.lock.Lock()
+	defer set.lock.Unlock()
+	var keys []*publicKey
+	for _, key := range set.keys {
+		if key.algorithm == algorithm && (keyID == "" || key.id == keyID) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, set.keys != nil
+}
+
+// lookup returns the cached signing keys for the said algorithm and key ID, refreshing them if
+// needed.
+func (set *KeySet) lookup(ctx context.Context, algorithm, keyID string) ([]*publicKey, error) {
+	set.refresh(ctx, set.ttl)
+	keys, loaded := set.find(algorithm, keyID)
+	if len(keys) == 0 {
+		set.refresh(ctx, minRefreshInterval)
+		keys, loaded = set.find(algorithm, keyID)
+	}
+	if !loaded {
+		return nil, ErrKeysUnavailable
+	}
+	return keys, nil
+}
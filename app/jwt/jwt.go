@@ -0,0 +1,257 @@
+// Package jwt provides verification of JWT bearer tokens, e.g. issued by an OIDC provider.
+package jwt
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/bodenr/vehicle-api/config"
+)
+
+const (
+	// AlgorithmRS256 is RSASSA-PKCS1-v1_5 using SHA-256.
+	AlgorithmRS256 = "RS256"
+
+	// AlgorithmES256 is ECDSA using P-256 and SHA-256.
+	AlgorithmES256 = "ES256"
+
+	// DefaultCacheTTL is how long JWKS keys are cached by default.
+	DefaultCacheTTL = 10 * time.Minute
+)
+
+var (
+	// ErrMalformedToken is the error used for tokens that can't be parsed.
+	ErrMalformedToken = errors.New("Malformed token")
+
+	// ErrUnsupportedAlgorithm is the error used for tokens not signed with RS256 or ES256.
+	ErrUnsupportedAlgorithm = errors.New("Unsupported token algorithm")
+
+	// ErrInvalidSignature is the error used for tokens whose signature doesn't verify.
+	ErrInvalidSignature = errors.New("Invalid token signature")
+
+	// ErrExpired is the error used for expired tokens or those without an expiry.
+	ErrExpired = errors.New("Token expired")
+
+	// ErrNotValidYet is the error used for tokens used before their not before time.
+	ErrNotValidYet = errors.New("Token not valid yet")
+
+	// ErrInvalidIssuer is the error used for tokens of another issuer.
+	ErrInvalidIssuer = errors.New("Invalid token issuer")
+
+	// ErrInvalidAudience is the error used for tokens of another audience.
+	ErrInvalidAudience = errors.New("Invalid token audience")
+)
+
+// Claims are the claims of a verified token.
+type Claims map[string]interface{}
+
+// String returns the said claim if it's a string or an empty string.
+func (claims Claims) String(name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// Subject returns the sub claim.
+func (claims Claims) Subject() string {
+	return claims.String("sub")
+}
+
+// Strings returns the said claim as a list, e.g. the aud or roles claims which are either a single
+// string or a list of strings.
+func (claims Claims) Strings(name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		var values []string
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// time returns the said numeric date claim and whether it's set.
+func (claims Claims) time(name string) (time.Time, bool, error) {
+	value, exists := claims[name]
+	if !exists {
+		return time.Time{}, false, nil
+	}
+	number, ok := value.(json.Number)
+	if !ok {
+		return time.Time{}, false, ErrMalformedToken
+	}
+	seconds, err := number.Float64()
+	if err != nil {
+		return time.Time{}, false, ErrMalformedToken
+	}
+	return time.Unix(int64(seconds), 0), true, nil
+}
+
+// header is the JOSE header of a token.
+type header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// Verifier verifies the signature, issuer, audience and expiry of tokens.
+type Verifier struct {
+	keys     *KeySet
+	issuer   string
+	audience []string
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+// NewVerifier creates a Verifier for the config, failing if the keys of the JWKS file or URL can't
+// be loaded.
+func NewVerifier(conf *config.JWTConfig) (*Verifier, error) {
+	if !conf.Enabled() {
+		return nil, fmt.Errorf("No JWKS file or URL set")
+	}
+	ttl := conf.CacheTTL
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	keys := NewKeySet(conf.JWKSFile, conf.JWKSURL, ttl)
+	if err := keys.Load(context.Background()); err != nil {
+		return nil, err
+	}
+	return &Verifier{
+		keys:     keys,
+		issuer:   conf.Issuer,
+		audience: conf.Audience,
+		leeway:   conf.Leeway,
+		now:      time.Now,
+	}, nil
+}
+
+// decodeSegment decodes a base64url encoded token segment.
+func decodeSegment(segment string) ([]byte, error) {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	return data, nil
+}
+
+// verifySignature verifies the signature of the signed content with the key.
+func verifySignature(key *publicKey, signed, signature []byte) bool {
+	hash := sha256.Sum256(signed)
+	switch pub := key.key.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], signature) == nil
+	case *ecdsa.PublicKey:
+		// NB: JWS ECDSA signatures are the fixed size r and s values rather than ASN.1
+		if len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(pub, hash[:], r, s)
+	}
+	return false
+}
+
+// Verify verifies the token, returning its claims.
+func (verifier *Verifier) Verify(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+	headerData, err := decodeSegment(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	jose := header{}
+	if err = json.Unmarshal(headerData, &jose); err != nil {
+		return nil, ErrMalformedToken
+	}
+	// NB: the algorithm is checked against those supported before looking up keys so tokens can't
+	// pick a weaker algorithm, e.g. none or HS256 using the public key as secret
+	if jose.Algorithm != AlgorithmRS256 && jose.Algorithm != AlgorithmES256 {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	signature, err := decodeSegment(parts[2])
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := verifier.keys.lookup(ctx, jose.Algorithm, jose.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, key := range keys {
+		if verifySignature(key, signed, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, ErrInvalidSignature
+	}
+
+	payload, err := decodeSegment(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	claims := Claims{}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err = decoder.Decode(&claims); err != nil {
+		return nil, ErrMalformedToken
+	}
+	if err = verifier.validate(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// validate validates the registered claims of the token.
+func (verifier *Verifier) validate(claims Claims) error {
+	now := verifier.now()
+	expiry, exists, err := claims.time("exp")
+	if err != nil {
+		return err
+	}
+	if !exists || !now.Before(expiry.Add(verifier.leeway)) {
+		return ErrExpired
+	}
+	notBefore, exists, err := claims.time("nbf")
+	if err != nil {
+		return err
+	}
+	if exists && now.Add(verifier.leeway).Before(notBefore) {
+		return ErrNotValidYet
+	}
+	if verifier.issuer != "" && claims.String("iss") != verifier.issuer {
+		return ErrInvalidIssuer
+	}
+	if len(verifier.audience) > 0 {
+		for _, audience := range claims.Strings("aud") {
+			for _, expected := range verifier.audience {
+				if audience == expected {
+					return nil
+				}
+			}
+		}
+		return ErrInvalidAudience
+	}
+	return nil
+}
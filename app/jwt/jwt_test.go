@@ -0,0 +1,329 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bodenr/vehicle-api/config"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testAudience = "vehicle-api"
+)
+
+var testNow = time.Unix(1600000000, 0)
+
+// testKeys are the signing keys of the test JWKS.
+type testKeys struct {
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testKeys{rsa: rsaKey, ec: ecKey}
+}
+
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// padded returns the big-endian bytes of a P-256 integer padded to 32 bytes.
+func padded(n *big.Int) []byte {
+	data := n.Bytes()
+	return append(make([]byte, 32-len(data)), data...)
+}
+
+// jwks returns the JWKS of the public keys.
+func (keys *testKeys) jwks(t *testing.T) []byte {
+	data, err := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": "rsa-1",
+				"use": "sig",
+				"alg": AlgorithmRS256,
+				"n":   encodeSegment(keys.rsa.N.Bytes()),
+				"e":   encodeSegment(big.NewInt(int64(keys.rsa.E)).Bytes()),
+			},
+			{
+				"kty": "EC",
+				"kid": "ec-1",
+				"crv": "P-256",
+				"x":   encodeSegment(padded(keys.ec.X)),
+				"y":   encodeSegment(padded(keys.ec.Y)),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// sign returns a token of the header and claims signed with the key of the header algorithm.
+func (keys *testKeys) sign(t *testing.T, header, claims map[string]interface{}) string {
+	headerData, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimsData, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := encodeSegment(headerData) + "." + encodeSegment(claimsData)
+	hash := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch header["alg"] {
+	case AlgorithmRS256:
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, keys.rsa, crypto.SHA256, hash[:]); err != nil {
+			t.Fatal(err)
+		}
+	case AlgorithmES256:
+		r, s, err := ecdsa.Sign(rand.Reader, keys.ec, hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = append(padded(r), padded(s)...)
+	}
+	return signed + "." + encodeSegment(signature)
+}
+
+// writeJWKS writes the JWKS to a file of a temporary directory, returning the file path.
+func writeJWKS(t *testing.T, data []byte) string {
+	dir, err := ioutil.TempDir("", "jwks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	file := filepath.Join(dir, "jwks.json")
+	if err = ioutil.WriteFile(file, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func newTestVerifier(t *testing.T, keys *testKeys) *Verifier {
+	verifier, err := NewVerifier(&config.JWTConfig{
+		JWKSFile: writeJWKS(t, keys.jwks(t)),
+		Issuer:   testIssuer,
+		Audience: []string{testAudience},
+		Leeway:   time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier.now = func() time.Time { return testNow }
+	return verifier
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub": "user-1",
+		"iss": testIssuer,
+		"aud": testAudience,
+		"exp": testNow.Add(time.Hour).Unix(),
+		"nbf": testNow.Add(-time.Hour).Unix(),
+	}
+}
+
+func TestVerify(t *testing.T) {
+	keys := newTestKeys(t)
+	verifier := newTestVerifier(t, keys)
+
+	rs256 := map[string]interface{}{"alg": AlgorithmRS256, "kid": "rsa-1"}
+	es256 := map[string]interface{}{"alg": AlgorithmES256, "kid": "ec-1"}
+	with := func(name string, value interface{}) map[string]interface{} {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+	unsigned := func(alg string) string {
+		header := encodeSegment([]byte(`{"alg":"` + alg + `","kid":"rsa-1"}`))
+		claims, _ := json.Marshal(validClaims())
+		return header + "." + encodeSegment(claims) + "."
+	}
+	withSignature := func(token string, signature []byte) string {
+		return token[:strings.LastIndex(token, ".")+1] + encodeSegment(signature)
+	}
+	esToken := keys.sign(t, es256, validClaims())
+	esSignature, _ := base64.RawURLEncoding.DecodeString(esToken[strings.LastIndex(esToken, ".")+1:])
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"rs256", keys.sign(t, rs256, validClaims()), nil},
+		{"es256", esToken, nil},
+		{"no kid", keys.sign(t, map[string]interface{}{"alg": AlgorithmES256}, validClaims()), nil},
+		{"audience list", keys.sign(t, rs256, with("aud", []string{"other", testAudience})), nil},
+		{"expired within leeway", keys.sign(t, rs256, with("exp", testNow.Add(-30*time.Second).Unix())), nil},
+		{"alg none", unsigned("none"), ErrUnsupportedAlgorithm},
+		{"alg HS256", unsigned("HS256"), ErrUnsupportedAlgorithm},
+		{"alg missing", unsigned(""), ErrUnsupportedAlgorithm},
+		{"kid mismatch", keys.sign(t, map[string]interface{}{"alg": AlgorithmRS256, "kid": "rsa-2"}, validClaims()),
+			ErrInvalidSignature},
+		{"kid of other algorithm", keys.sign(t, map[string]interface{}{"alg": AlgorithmRS256, "kid": "ec-1"},
+			validClaims()), ErrInvalidSignature},
+		{"exp missing", keys.sign(t, rs256, with("exp", nil)), ErrExpired},
+		{"exp passed", keys.sign(t, rs256, with("exp", testNow.Add(-2*time.Minute).Unix())), ErrExpired},
+		{"exp malformed", keys.sign(t, rs256, with("exp", "tomorrow")), ErrMalformedToken},
+		{"nbf in future", keys.sign(t, rs256, with("nbf", testNow.Add(2*time.Minute).Unix())), ErrNotValidYet},
+		{"iss missing", keys.sign(t, rs256, with("iss", nil)), ErrInvalidIssuer},
+		{"iss mismatch", keys.sign(t, rs256, with("iss", "https://other.example.com")), ErrInvalidIssuer},
+		{"aud missing", keys.sign(t, rs256, with("aud", nil)), ErrInvalidAudience},
+		{"aud mismatch", keys.sign(t, rs256, with("aud", []string{"other"})), ErrInvalidAudience},
+		{"es256 signature empty", withSignature(esToken, nil), ErrInvalidSignature},
+		{"es256 signature truncated", withSignature(esToken, esSignature[:63]), ErrInvalidSignature},
+		{"es256 signature padded", withSignature(esToken, append(esSignature, 0)), ErrInvalidSignature},
+		{"es256 signature zero", withSignature(esToken, make([]byte, 64)), ErrInvalidSignature},
+		{"es256 signature swapped", withSignature(esToken, append(esSignature[32:], esSignature[:32]...)),
+			ErrInvalidSignature},
+		{"es256 signature not base64url", esToken[:strings.LastIndex(esToken, ".")+1] + "!!", ErrMalformedToken},
+		{"es256 signature of rsa size", withSignature(esToken, []byte(strings.Repeat("x", 256))), ErrInvalidSignature},
+		{"payload tampered", func() string {
+			parts := strings.Split(esToken, ".")
+			claims, _ := json.Marshal(with("sub", "admin"))
+			return parts[0] + "." + encodeSegment(claims) + "." + parts[2]
+		}(), ErrInvalidSignature},
+		{"two segments", "a.b", ErrMalformedToken},
+		{"header not json", encodeSegment([]byte("{")) + ".e30.", ErrMalformedToken},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims, err := verifier.Verify(context.Background(), test.token)
+			if err != test.err {
+				t.Fatalf("expected error %v, got %v", test.err, err)
+			}
+			if err == nil && claims.Subject() != "user-1" {
+				t.Fatalf("expected subject user-1, got %s", claims.Subject())
+			}
+		})
+	}
+}
+
+func TestNewVerifierLoadsKeys(t *testing.T) {
+	keys := newTestKeys(t)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		conf config.JWTConfig
+		ok   bool
+	}{
+		{"file", config.JWTConfig{JWKSFile: writeJWKS(t, keys.jwks(t))}, true},
+		{"missing file", config.JWTConfig{JWKSFile: filepath.Join(os.TempDir(), "missing-jwks.json")}, false},
+		{"no signing keys", config.JWTConfig{JWKSFile: writeJWKS(t, []byte(`{"keys":[]}`))}, false},
+		{"not json", config.JWTConfig{JWKSFile: writeJWKS(t, []byte(`keys`))}, false},
+		{"url not found", config.JWTConfig{JWKSURL: server.URL}, false},
+		{"unset", config.JWTConfig{}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewVerifier(&test.conf)
+			if test.ok && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			} else if !test.ok && err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestKeySetRefresh(t *testing.T) {
+	keys := newTestKeys(t)
+	jwks := keys.jwks(t)
+	var fetches int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		// the first fetch is the load, later fetches are blocked until released
+		if atomic.AddInt32(&fetches, 1) > 1 {
+			<-release
+		}
+		writer.Write(jwks)
+	}))
+	defer server.Close()
+
+	set := NewKeySet("", server.URL, time.Hour)
+	if err := set.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// a known key doesn't refresh the keys
+	if found, err := set.lookup(context.Background(), AlgorithmRS256, "rsa-1"); err != nil || len(found) != 1 {
+		t.Fatalf("expected the rsa key, got %v, %v", found, err)
+	}
+
+	// an unknown key is only refreshed once the minimum refresh interval passes
+	set.lock.Lock()
+	set.refreshedAt = time.Now().Add(-minRefreshInterval)
+	set.lock.Unlock()
+
+	// a canceled request gives up waiting without failing the refresh of the others
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if found, err := set.lookup(canceled, AlgorithmRS256, "rsa-2"); err != nil || len(found) != 0 {
+		t.Fatalf("expected no keys, got %v, %v", found, err)
+	}
+
+	var wait sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			if _, err := set.lookup(context.Background(), AlgorithmRS256, "rsa-2"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	// lookups of known keys aren't blocked by the refresh
+	if found, err := set.lookup(context.Background(), AlgorithmES256, "ec-1"); err != nil || len(found) != 1 {
+		t.Fatalf("expected the ec key, got %v, %v", found, err)
+	}
+	close(release)
+	wait.Wait()
+
+	if n := atomic.LoadInt32(&fetches); n != 2 {
+		t.Fatalf("expected a single refresh, got %d fetches", n-1)
+	}
+	set.lock.Lock()
+	defer set.lock.Unlock()
+	if time.Since(set.refreshedAt) >= minRefreshInterval || set.refreshing != nil {
+		t.Fatal("expected the refresh to be done")
+	}
+}
@@ -0,0 +1,165 @@
+// Package lifecycle provides application component lifecycle management.
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/bodenr/vehicle-api/log"
+)
+
+// Component is a part of the application, such as the database or a server, that's started and
+// stopped by a Supervisor.
+type Component interface {
+	// Name of the component used in logs and errors.
+	Name() string
+
+	// Start prepares the component without blocking for long, e.g. connecting or creating listeners;
+	// the context is canceled when the Supervisor is stopped while starting.
+	Start(ctx context.Context) error
+
+	// Stop stops the component, giving up on any graceful shutdown when the context is done.
+	Stop(ctx context.Context) error
+}
+
+// Runner is optionally implemented by components that do their work in the foreground until stopped,
+// such as servers. Run returning, with or without error, before the component is stopped is treated
+// as a failure.
+type Runner interface {
+	Run() error
+}
+
+// FuncComponent is a Component implemented by functions, either of which may be nil.
+type FuncComponent struct {
+	ComponentName string
+	StartFunc     func(ctx context.Context) error
+	StopFunc      func(ctx context.Context) error
+}
+
+// Name returns the component name.
+func (component *FuncComponent) Name() string {
+	return component.ComponentName
+}
+
+// Start calls the StartFunc if set.
+func (component *FuncComponent) Start(ctx context.Context) error {
+	if component.StartFunc == nil {
+		return nil
+	}
+	return component.StartFunc(ctx)
+}
+
+// Stop calls the StopFunc if set.
+func (component *FuncComponent) Stop(ctx context.Context) error {
+	if component.StopFunc == nil {
+		return nil
+	}
+	return component.StopFunc(ctx)
+}
+
+// Supervisor starts components in the order they're registered and stops them in reverse order
+// when a signal is received or any component fails.
+type Supervisor struct {
+	// StopTimeout is the deadline shared by all components to stop.
+	StopTimeout time.Duration
+	components  []Component
+}
+
+// NewSupervisor creates a Supervisor with the said stop timeout.
+func NewSupervisor(stopTimeout time.Duration) *Supervisor {
+	return &Supervisor{
+		StopTimeout: stopTimeout,
+	}
+}
+
+// Register adds components to start after those already registered.
+func (supervisor *Supervisor) Register(components ...Component) {
+	supervisor.components = append(supervisor.components, components...)
+}
+
+// componentError is the failure of a running component.
+type componentError struct {
+	name string
+	err  error
+}
+
+// Run starts the components and blocks until one of the said signals is received or a component
+// fails, then stops the started components. Signals received while starting cancel the start of
+// the current component and skip the others. The error of the failed component, if any, is returned.
+func (supervisor *Supervisor) Run(signals ...os.Signal) error {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, signals...)
+	defer signal.Stop(sig)
+
+	// NB: signals are handled from the start so a slow start, e.g. connecting, can be interrupted
+	stopping, stop := context.WithCancel(context.Background())
+	defer stop()
+	go func() {
+		select {
+		case termSig := <-sig:
+			log.Log.Info().Str(log.Signal, termSig.String()).Msg("stopping components")
+			stop()
+		case <-stopping.Done():
+		}
+	}()
+
+	failed := make(chan componentError, len(supervisor.components))
+	var err error
+	started := 0
+	for _, component := range supervisor.components {
+		if stopping.Err() != nil {
+			break
+		}
+		log.Log.Info().Str(log.Component, component.Name()).Msg("starting component")
+		if err = component.Start(stopping); err != nil {
+			if stopping.Err() != nil {
+				log.Log.Info().Str(log.Component, component.Name()).Msg("interrupted starting component")
+				err = nil
+				break
+			}
+			log.Log.Err(err).Str(log.Component, component.Name()).Msg("failed to start component")
+			err = fmt.Errorf("Failed to start %s: %v", component.Name(), err)
+			break
+		}
+		started++
+		if runner, ok := component.(Runner); ok {
+			go func(name string) {
+				runErr := runner.Run()
+				if runErr == nil {
+					runErr = fmt.Errorf("%s stopped unexpectedly", name)
+				}
+				failed <- componentError{name: name, err: runErr}
+			}(component.Name())
+		}
+	}
+
+	if err == nil && stopping.Err() == nil {
+		select {
+		case <-stopping.Done():
+		case failure := <-failed:
+			log.Log.Err(failure.err).Str(log.Component, failure.name).Msg("component failed, stopping components")
+			err = fmt.Errorf("%s failed: %v", failure.name, failure.err)
+		}
+	}
+
+	ctx, halt := context.WithTimeout(context.Background(), supervisor.StopTimeout)
+	defer halt()
+	Stop(ctx, supervisor.components[:started]...)
+	return err
+}
+
+// Stop stops the components in reverse order, logging any failure; it's also used to release
+// components that were created but never run by a Supervisor.
+func Stop(ctx context.Context, components ...Component) {
+	for i := len(components) - 1; i >= 0; i-- {
+		component := components[i]
+		if err := component.Stop(ctx); err != nil {
+			log.Log.Err(err).Str(log.Component, component.Name()).Msg("failed to stop component")
+		} else {
+			log.Log.Info().Str(log.Component, component.Name()).Msg("stopped component")
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package log
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+// FromContext returns the request logger carried by the context, falling back to the application
+// logger. Request loggers are set by the REST and GRPC servers and include the correlation fields
+// of the request, e.g. req_id, so log entries of a request can be tied together.
+func FromContext(ctx context.Context) *zerolog.Logger {
+	if logger := zerolog.Ctx(ctx); logger.GetLevel() != zerolog.Disabled {
+		return logger
+	}
+	return &Log
+}
+
+// UpdateContext adds fields to the request logger carried by the context, e.g. the principal once
+// authenticated, so they're also included in entries logged by outer handlers such as the access
+// log. The application logger is never updated, so it's a no-op without a request logger.
+func UpdateContext(ctx context.Context, update func(c zerolog.Context) zerolog.Context) {
+	if logger := zerolog.Ctx(ctx); logger.GetLevel() != zerolog.Disabled {
+		logger.UpdateContext(update)
+	}
+}
+
+// WithContext returns a copy of the context carrying the said request logger.
+func WithContext(ctx context.Context, logger zerolog.Logger) context.Context {
+	return logger.WithContext(ctx)
+}
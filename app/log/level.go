@@ -0,0 +1,156 @@
+package log
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+)
+
+// levels holds the default log level and the per package overrides.
+var levels = struct {
+	sync.RWMutex
+	level    zerolog.Level
+	packages map[string]zerolog.Level
+}{
+	level:    zerolog.InfoLevel,
+	packages: map[string]zerolog.Level{},
+}
+
+// hasPackageLevels is 1 when package levels are set so the level hook is a no-op otherwise.
+var hasPackageLevels int32
+
+// Level returns the default log level.
+func Level() zerolog.Level {
+	levels.RLock()
+	defer levels.RUnlock()
+	return levels.level
+}
+
+// PackageLevels returns a copy of the package level overrides keyed by package name.
+func PackageLevels() map[string]zerolog.Level {
+	levels.RLock()
+	defer levels.RUnlock()
+	packages := make(map[string]zerolog.Level, len(levels.packages))
+	for pkg, level := range levels.packages {
+		packages[pkg] = level
+	}
+	return packages
+}
+
+// SetLevel sets the default log level of packages without an override.
+func SetLevel(level zerolog.Level) {
+	levels.Lock()
+	defer levels.Unlock()
+	levels.level = level
+	applyLevels()
+}
+
+// SetPackageLevel overrides the log level of the said package, e.g. resources, taking precedence
+// over the default level; the package name is the last element of its import path.
+func SetPackageLevel(pkg string, level zerolog.Level) {
+	levels.Lock()
+	defer levels.Unlock()
+	levels.packages[pkg] = level
+	applyLevels()
+}
+
+// ClearPackageLevel removes the log level override of the said package.
+func ClearPackageLevel(pkg string) {
+	levels.Lock()
+	defer levels.Unlock()
+	delete(levels.packages, pkg)
+	applyLevels()
+}
+
+// ParseLevel parses a log level name, e.g. debug; unlike zerolog an empty name isn't valid.
+func ParseLevel(name string) (zerolog.Level, error) {
+	if name == "" {
+		return zerolog.NoLevel, fmt.Errorf("Log level not set")
+	}
+	level, err := zerolog.ParseLevel(strings.ToLower(name))
+	if err != nil {
+		return zerolog.NoLevel, fmt.Errorf("Invalid log level: %s", name)
+	}
+	return level, nil
+}
+
+// parsePackageLevel sets the package level of a pkg=level pair, e.g. resources=debug.
+func parsePackageLevel(packageLevel string) error {
+	parts := strings.SplitN(packageLevel, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return fmt.Errorf("Invalid package log level: %s", packageLevel)
+	}
+	level, err := ParseLevel(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return err
+	}
+	SetPackageLevel(strings.TrimSpace(parts[0]), level)
+	return nil
+}
+
+// applyLevels sets the zerolog global level to the lowest of the levels so entries of packages
+// with a lower override aren't dropped before reaching the level hook; levels must be locked.
+func applyLevels() {
+	lowest := levels.level
+	for _, level := range levels.packages {
+		if level < lowest {
+			lowest = level
+		}
+	}
+	zerolog.SetGlobalLevel(lowest)
+	if len(levels.packages) > 0 {
+		atomic.StoreInt32(&hasPackageLevels, 1)
+	} else {
+		atomic.StoreInt32(&hasPackageLevels, 0)
+	}
+}
+
+// levelHook discards entries below the level of the package logging them.
+type levelHook struct{}
+
+// Run discards the entry if its level is below the level of the calling package.
+func (levelHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	if atomic.LoadInt32(&hasPackageLevels) == 0 {
+		return
+	}
+	pkg := callerPackage()
+	levels.RLock()
+	threshold, ok := levels.packages[pkg]
+	if !ok {
+		threshold = levels.level
+	}
+	levels.RUnlock()
+	if level < threshold {
+		e.Discard()
+	}
+}
+
+// callerPackage returns the name of the first package on the stack that isn't zerolog or this one.
+func callerPackage() string {
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		pkg := packagePath(frame.Function)
+		if !strings.HasPrefix(pkg, "github.com/rs/zerolog") && pkg != "github.com/bodenr/vehicle-api/log" {
+			return pkg[strings.LastIndex(pkg, "/")+1:]
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// packagePath returns the import path of a function name, e.g. github.com/bodenr/vehicle-api/svr
+// for github.com/bodenr/vehicle-api/svr.(*RestServer).Run.
+func packagePath(function string) string {
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+	return function
+}
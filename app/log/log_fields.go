@@ -1,18 +1,93 @@
 package log
 
 const (
+	// Allowed log key.
+	Allowed = "allowed"
+
 	// Binary log key.
 	Binary = "binary"
 
+	// CertFile log key.
+	CertFile = "cert_file"
+
+	// Code log key.
+	Code = "code"
+
+	// Component log key.
+	Component = "component"
+
+	// DrainDelay log key.
+	DrainDelay = "drain_delay"
+
+	// Duration log key.
+	Duration = "duration"
+
+	// Exporter log key.
+	Exporter = "exporter"
+
+	// Format log key.
+	Format = "format"
+
 	// Hostname log key.
 	Hostname = "hostname"
 
+	// IP log key.
+	IP = "ip"
+
+	// KeyID log key.
+	KeyID = "key_id"
+
+	// LogLevel log key.
+	LogLevel = "log_level"
+
+	// Method log key.
+	Method = "method"
+
+	// Panic log key.
+	Panic = "panic"
+
+	// Packages log key.
+	Packages = "packages"
+
+	// Peer log key.
+	Peer = "peer"
+
+	// Principal log key.
+	Principal = "principal"
+
 	// Query log key.
 	Query = "query"
 
+	// RequestID log key.
+	RequestID = "req_id"
+
+	// Resource log key.
+	Resource = "resource"
+
+	// Roles log key.
+	Roles = "roles"
+
 	// Signal log key.
 	Signal = "signal"
 
+	// SpanID log key.
+	SpanID = "span_id"
+
+	// Stack log key.
+	Stack = "stack"
+
+	// Tenant log key.
+	Tenant = "tenant"
+
+	// TraceID log key.
+	TraceID = "trace_id"
+
+	// UserAgent log key.
+	UserAgent = "user_agent"
+
+	// Verb log key.
+	Verb = "verb"
+
 	// VIN log key.
 	VIN = "vin"
 )
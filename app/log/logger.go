@@ -2,8 +2,10 @@
 package log
 
 import (
+	"io"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/bodenr/vehicle-api/config"
 	"github.com/rs/zerolog"
@@ -12,22 +14,73 @@ import (
 // Log is the singleton application wide logger.
 var Log zerolog.Logger
 
+// sampler samples high volume entries, e.g. access logs; nil if sampling is disabled.
+var sampler zerolog.Sampler
+
 func init() {
 	// setup the singleton logger
-	level, err := zerolog.ParseLevel(config.GetEnv("LOG_LEVEL", "info"))
+	conf := config.LogConfig{
+		Level:          "info",
+		Format:         config.LogFormatJSON,
+		SamplingPeriod: time.Second,
+	}
+	conf.Load()
+
+	level, err := ParseLevel(conf.Level)
 	if err != nil {
 		level = zerolog.InfoLevel
 	}
-	zerolog.SetGlobalLevel(level)
+	SetLevel(level)
 	binary := filepath.Base(os.Args[0])
 	hostname, hErr := os.Hostname()
 	if hErr != nil {
 		hostname = "unknown"
 	}
-	Log = zerolog.New(os.Stdout).With().Timestamp().Caller().Str(
+	SetRedactFields(conf.RedactFields)
+	var writer io.Writer = os.Stdout
+	if conf.Format == config.LogFormatConsole {
+		writer = zerolog.ConsoleWriter{Out: os.Stdout}
+	}
+	// NB: entries are redacted as json so before being formatted for the console
+	writer = redactWriter{out: writer}
+	// NB: the level hook is added first so loggers derived from Log inherit it
+	Log = zerolog.New(writer).Hook(levelHook{}).With().Timestamp().Caller().Str(
 		Binary, binary).Str(Hostname, hostname).Logger()
 
 	if err != nil {
 		Log.Warn().Msg("failed to parse log level, falling back to info")
 	}
+	if conf.Format != config.LogFormatJSON && conf.Format != config.LogFormatConsole {
+		Log.Warn().Str(Format, conf.Format).Msg("unsupported log format, falling back to json")
+	}
+	for _, packageLevel := range conf.PackageLevels {
+		if err := parsePackageLevel(packageLevel); err != nil {
+			Log.Warn().Err(err).Msg("failed to parse package log level")
+		}
+	}
+	if conf.SamplingEnabled() {
+		burst := &zerolog.BurstSampler{
+			Burst:  uint32(conf.SamplingBurst),
+			Period: conf.SamplingPeriod,
+		}
+		// NB: entries past the burst are dropped without a next sampler
+		if conf.SamplingRate > 0 {
+			burst.NextSampler = &zerolog.BasicSampler{N: uint32(conf.SamplingRate)}
+		}
+		sampler = burst
+	}
+}
+
+// Sampled returns the logger sampling its entries as per the LOG_SAMPLING_ env vars; it's meant
+// for high volume entries such as access logs. Warnings and errors are never sampled.
+func Sampled(logger *zerolog.Logger) *zerolog.Logger {
+	if sampler == nil {
+		return logger
+	}
+	sampled := logger.Sample(zerolog.LevelSampler{
+		TraceSampler: sampler,
+		DebugSampler: sampler,
+		InfoSampler:  sampler,
+	})
+	return &sampled
 }
@@ -2,64 +2,126 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
-	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/bodenr/vehicle-api/config"
 	"github.com/bodenr/vehicle-api/db"
+	"github.com/bodenr/vehicle-api/lifecycle"
 	"github.com/bodenr/vehicle-api/log"
+	"github.com/bodenr/vehicle-api/metrics"
 	"github.com/bodenr/vehicle-api/resources"
 	"github.com/bodenr/vehicle-api/svr"
+	"github.com/bodenr/vehicle-api/tracing"
 )
 
-func startRestApi(conf *config.HTTPConfig) <-chan bool {
-	serverStop := make(chan bool, 1)
-	sigStop := make(chan os.Signal)
-	signal.Notify(sigStop, syscall.SIGTERM, syscall.SIGKILL, syscall.SIGINT)
+// defaultTLSConfig returns the TLS defaults shared by the servers; TLS is enabled by setting a certificate.
+func defaultTLSConfig() config.TLSConfig {
+	return config.TLSConfig{
+		MinVersion:     "1.2",
+		ReloadInterval: 30 * time.Second,
+	}
+}
 
-	server := svr.NewRestServer(conf, resources.StoredVehicle{})
+// stopTimeout is the deadline shared by all components to stop.
+const stopTimeout = 20 * time.Second
 
-	go func() {
-		if err := server.Run(); err != nil {
-			panic(err)
-		}
-	}()
+// errSchemaNotApplied is the readiness error until the database schema has been created.
+var errSchemaNotApplied = errors.New("Schema not applied")
 
-	go server.WaitForShutdown(sigStop, serverStop)
-	return serverStop
+// newDatabase creates the database component which connects and creates the schema on start.
+func newDatabase(conf *config.DatabaseConfig, vehicles resources.StoredVehicle,
+	keys resources.StoredAPIKey, health *svr.Health) lifecycle.Component {
+
+	var schemaApplied int32
+	health.AddCheck("database", db.Ping)
+	health.AddCheck("schema", func(context.Context) error {
+		if atomic.LoadInt32(&schemaApplied) == 0 {
+			return errSchemaNotApplied
+		}
+		return nil
+	})
+	return &lifecycle.FuncComponent{
+		ComponentName: "database",
+		StartFunc: func(ctx context.Context) error {
+			// NB: it can take up to a few seconds until the database is accepting connections when
+			// started using docker compose, so hold off on starting the servers until we're sure we
+			// can connect to the database
+			if err := db.Initialize(ctx, conf); err != nil {
+				return err
+			}
+			// NB: the database isn't stopped by the supervisor unless started, so close it if the
+			// schema can't be applied
+			err := vehicles.CreateSchema()
+			if err == nil {
+				err = keys.CreateSchema()
+			}
+			if err != nil {
+				db.Close()
+				return err
+			}
+			atomic.StoreInt32(&schemaApplied, 1)
+			return nil
+		},
+		StopFunc: func(context.Context) error {
+			return db.Close()
+		},
+	}
 }
 
-func startGrpcServer(conf *config.GrpcConfig) <-chan bool {
-	serverStop := make(chan bool, 1)
-	sigStop := make(chan os.Signal)
-	signal.Notify(sigStop, syscall.SIGTERM, syscall.SIGKILL, syscall.SIGINT)
+// newServers creates the server components for the configs, either a single MuxServer or separate
+// REST and GRPC servers; servers already created are stopped if creating another fails.
+func newServers(httpConf *config.HTTPConfig, grpcConf *config.GrpcConfig, muxConf *config.MuxConfig,
+	vehicles resources.StoredVehicle, health *svr.Health, auth *svr.Auth,
+	tenancy *svr.Tenancy) ([]lifecycle.Component, error) {
 
 	handler := svr.GrpcHandler{
-		Resource: resources.StoredVehicle{},
+		Resource: vehicles,
 	}
-	server, err := svr.NewGrpcServer(conf, &handler)
-	if err != nil {
-		log.Log.Err(err).Msg("Failed to create grpc server")
-		panic(err)
+	if muxConf.Enabled() {
+		server, err := svr.NewMuxServer(muxConf, *httpConf, *grpcConf, &handler, health, auth, tenancy, vehicles)
+		if err != nil {
+			return nil, err
+		}
+		return []lifecycle.Component{server}, nil
 	}
 
-	go func() {
-		if err := server.Run(); err != nil {
-			log.Log.Err(err).Msg("Failed to start grpc server")
-			panic(err)
-		}
-	}()
+	restServer, err := svr.NewRestServer(httpConf, health, auth, tenancy, vehicles)
+	if err != nil {
+		return nil, err
+	}
+	grpcServer, err := svr.NewGrpcServer(grpcConf, &handler, health, auth, tenancy)
+	if err != nil {
+		stopComponents(restServer)
+		return nil, err
+	}
+	return []lifecycle.Component{restServer, grpcServer}, nil
+}
 
-	go server.WaitForShutdown(sigStop, serverStop)
-	return serverStop
+// stopComponents stops components that were created but won't be run, e.g. releasing their
+// certificate reloaders, when failing to create the others.
+func stopComponents(components ...lifecycle.Component) {
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+	lifecycle.Stop(ctx, components...)
 }
 
 func main() {
 	log.Log.Info().Msg("Service starting")
+	if err := run(); err != nil {
+		log.Log.Err(err).Msg("Service failed")
+		os.Exit(1)
+	}
+	log.Log.Info().Msg("Stopped service")
+}
 
-	// init database
+// run creates the components of the service and runs them until stopped by a signal or failure.
+func run() error {
 	dbConfig := config.DatabaseConfig{
 		DatabaseName:   "vehicles",
 		Username:       "goapp",
@@ -68,33 +130,100 @@ func main() {
 		ConnectBackoff: time.Duration(1) * time.Second,
 	}
 	dbConfig.Load()
-	// NB: it can take up to a few seconds until the database is accepting connections when
-	// started using docker compose, so hold off on starting the servers until we're sure we
-	// can connect to the database
-	if err := db.Initialize(&dbConfig); err != nil {
-		log.Log.Err(err).Msg("Failed to initialize database")
-		panic(err)
+
+	vehicleConf := config.VehicleConfig{}
+	vehicleConf.Load()
+
+	tenantConf := config.TenantConfig{
+		Header: svr.DefaultTenantHeader,
+	}
+	tenantConf.Load()
+	tenancy := svr.NewTenancy(&tenantConf)
+
+	vehicles := resources.StoredVehicle{
+		Colors:           vehicleConf.Colors,
+		RowLevelSecurity: tenantConf.RowLevelSecurity,
 	}
-	resources.StoredVehicle{}.CreateSchema()
-	defer db.Close()
 
-	// init rest api server
 	httpConfig := config.HTTPConfig{
-		Address: ":8080",
+		Address:     ":8080",
+		ErrorFormat: config.ErrorFormatProblem,
+		TLS:         defaultTLSConfig(),
 	}
 	httpConfig.Load()
-	httpStopped := startRestApi(&httpConfig)
 
-	// init grpc server
 	grpcConf := config.GrpcConfig{
 		Address: ":10010",
+		TLS:     defaultTLSConfig(),
 	}
 	grpcConf.Load()
-	grpcStopped := startGrpcServer(&grpcConf)
 
-	// wait for server stop
-	<-httpStopped
-	<-grpcStopped
+	muxConf := config.MuxConfig{
+		TLS: defaultTLSConfig(),
+	}
+	muxConf.Load()
 
-	log.Log.Info().Msg("Stopped service")
+	metricsConf := config.MetricsConfig{
+		Path:      metrics.DefaultPath,
+		Namespace: metrics.DefaultNamespace,
+	}
+	metricsConf.Load()
+	if err := metrics.Init(&metricsConf); err != nil {
+		return fmt.Errorf("Failed to initialize metrics: %v", err)
+	}
+	if err := metrics.RegisterDBStats(db.Stats); err != nil {
+		return fmt.Errorf("Failed to register database metrics: %v", err)
+	}
+
+	tracingConf := config.TracingConfig{
+		Exporter:    config.TracingExporterNone,
+		ServiceName: tracing.DefaultServiceName,
+		SampleRatio: 1,
+	}
+	tracingConf.Load()
+	if err := tracing.Init(&tracingConf); err != nil {
+		return fmt.Errorf("Failed to initialize tracing: %v", err)
+	}
+	tracer := &lifecycle.FuncComponent{
+		ComponentName: "tracing",
+		StopFunc:      tracing.Shutdown,
+	}
+
+	keys := resources.StoredAPIKey{}
+	authConf := config.AuthConfig{
+		UsageInterval: svr.DefaultKeyUsageInterval,
+		JWT: config.JWTConfig{
+			RolesClaim:  svr.DefaultRolesClaim,
+			TenantClaim: svr.DefaultTenantClaim,
+		},
+	}
+	authConf.Load()
+	auth, err := svr.NewAuth(&authConf, keys)
+	if err != nil {
+		stopComponents(tracer)
+		return fmt.Errorf("Failed to initialize authentication: %v", err)
+	}
+
+	healthConf := config.HealthConfig{
+		CheckInterval: 10 * time.Second,
+		DrainDelay:    5 * time.Second,
+	}
+	healthConf.Load()
+	health := svr.NewHealth(&healthConf)
+
+	servers, err := newServers(&httpConfig, &grpcConf, &muxConf, vehicles, health, auth, tenancy)
+	if err != nil {
+		stopComponents(tracer)
+		return fmt.Errorf("Failed to create servers: %v", err)
+	}
+
+	// NB: components are stopped in reverse, so readiness fails first, the database is closed
+	// after the servers drain and spans are flushed last
+	supervisor := lifecycle.NewSupervisor(stopTimeout)
+	supervisor.Register(tracer)
+	supervisor.Register(newDatabase(&dbConfig, vehicles, keys, health))
+	supervisor.Register(servers...)
+	supervisor.Register(health)
+
+	return supervisor.Run(syscall.SIGTERM, syscall.SIGINT)
 }
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// dbStatsCollector collects database connection pool statistics when scraped.
+type dbStatsCollector struct {
+	stats        func() sql.DBStats
+	open         *prometheus.Desc
+	inUse        *prometheus.Desc
+	idle         *prometheus.Desc
+	waitCount    *prometheus.Desc
+	waitDuration *prometheus.Desc
+}
+
+// newDBStatsCollector creates a collector for the statistics returned by the function.
+func newDBStatsCollector(namespace string, stats func() sql.DBStats) *dbStatsCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db", name), help, nil, nil)
+	}
+	return &dbStatsCollector{
+		stats:        stats,
+		open:         desc("open_connections", "Established database connections, both in use and idle."),
+		inUse:        desc("in_use_connections", "Database connections currently in use."),
+		idle:         desc("idle_connections", "Idle database connections."),
+		waitCount:    desc("wait_count_total", "Total database connections waited for."),
+		waitDuration: desc("wait_duration_seconds_total", "Total time blocked waiting for a database connection."),
+	}
+}
+
+// Describe sends the descriptors of the collected metrics.
+func (collector *dbStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- collector.open
+	ch <- collector.inUse
+	ch <- collector.idle
+	ch <- collector.waitCount
+	ch <- collector.waitDuration
+}
+
+// Collect sends the current statistics.
+func (collector *dbStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := collector.stats()
+	ch <- prometheus.MustNewConstMetric(collector.open, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(collector.inUse, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(collector.idle, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(collector.waitCount, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(collector.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+}
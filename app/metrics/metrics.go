@@ -0,0 +1,242 @@
+// Package metrics provides Prometheus metrics for the application.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bodenr/vehicle-api/config"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultPath is the default path of the metrics endpoint.
+	DefaultPath = "/metrics"
+
+	// DefaultNamespace is the default namespace of metric names.
+	DefaultNamespace = "vehicle_api"
+
+	// grpcTypeUnary is the type label of unary GRPC requests.
+	grpcTypeUnary = "unary"
+
+	// grpcTypeStream is the type label of streaming GRPC requests.
+	grpcTypeStream = "stream"
+)
+
+// collectors holds the application metrics.
+type collectors struct {
+	registry      *prometheus.Registry
+	namespace     string
+	path          string
+	httpRequests  *prometheus.CounterVec
+	httpDuration  *prometheus.HistogramVec
+	grpcRequests  *prometheus.CounterVec
+	grpcDuration  *prometheus.HistogramVec
+	queryDuration *prometheus.HistogramVec
+}
+
+// metrics is nil unless metrics are enabled.
+var (
+	metrics *collectors
+	lock    sync.RWMutex
+)
+
+// Init creates and registers the application metrics as per the config; metrics are disabled and
+// the instrumentation is a no-op unless Init is called with an enabled config.
+func Init(conf *config.MetricsConfig) error {
+	if !conf.Enabled {
+		return nil
+	}
+	namespace := conf.Namespace
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+	path := conf.Path
+	if path == "" {
+		path = DefaultPath
+	}
+	buckets := func(buckets []float64) []float64 {
+		if len(buckets) == 0 {
+			return prometheus.DefBuckets
+		}
+		return buckets
+	}
+
+	c := &collectors{
+		registry:  prometheus.NewRegistry(),
+		namespace: namespace,
+		path:      path,
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "REST API requests by route template, method and status.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "REST API request latency by route template, method and status.",
+			Buckets:   buckets(conf.HTTPBuckets),
+		}, []string{"route", "method", "status"}),
+		grpcRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "requests_total",
+			Help:      "GRPC requests by method, type and code.",
+		}, []string{"method", "type", "code"}),
+		grpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "request_duration_seconds",
+			Help:      "GRPC request latency by method, type and code.",
+			Buckets:   buckets(conf.GrpcBuckets),
+		}, []string{"method", "type", "code"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "query_duration_seconds",
+			Help:      "Database query latency by store operation.",
+			Buckets:   buckets(conf.QueryBuckets),
+		}, []string{"operation"}),
+	}
+	for _, collector := range []prometheus.Collector{
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		c.httpRequests, c.httpDuration, c.grpcRequests, c.grpcDuration, c.queryDuration,
+	} {
+		if err := c.registry.Register(collector); err != nil {
+			return err
+		}
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	metrics = c
+	return nil
+}
+
+// get returns the metrics or nil if they're disabled.
+func get() *collectors {
+	lock.RLock()
+	defer lock.RUnlock()
+	return metrics
+}
+
+// Enabled returns true if metrics are enabled.
+func Enabled() bool {
+	return get() != nil
+}
+
+// RegisterDBStats registers metrics for the connection pool statistics returned by the function.
+func RegisterDBStats(stats func() sql.DBStats) error {
+	c := get()
+	if c == nil {
+		return nil
+	}
+	return c.registry.Register(newDBStatsCollector(c.namespace, stats))
+}
+
+// BindRoutes binds the metrics endpoint to the router if metrics are enabled.
+func BindRoutes(router *mux.Router) {
+	c := get()
+	if c == nil {
+		return
+	}
+	router.Handle(c.path, promhttp.HandlerFor(c.registry, promhttp.HandlerOpts{})).Methods(http.MethodGet)
+}
+
+// statusWriter records the status code written to a ResponseWriter.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code before writing it.
+func (writer *statusWriter) WriteHeader(code int) {
+	writer.status = code
+	writer.ResponseWriter.WriteHeader(code)
+}
+
+// HTTPHandler is middleware recording REST API request metrics labeled by the route template; it
+// must be used on a router so the matched route is known.
+func HTTPHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		c := get()
+		if c == nil {
+			next.ServeHTTP(writer, request)
+			return
+		}
+		route := "unknown"
+		if current := mux.CurrentRoute(request); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+		start := time.Now()
+		recorder := &statusWriter{ResponseWriter: writer, status: http.StatusOK}
+		next.ServeHTTP(recorder, request)
+
+		labels := prometheus.Labels{
+			"route":  route,
+			"method": request.Method,
+			"status": strconv.Itoa(recorder.status),
+		}
+		c.httpRequests.With(labels).Inc()
+		c.httpDuration.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
+
+// observeGrpc records the metrics of a GRPC request.
+func observeGrpc(method, grpcType string, start time.Time, err error) {
+	c := get()
+	if c == nil {
+		return
+	}
+	labels := prometheus.Labels{
+		"method": method,
+		"type":   grpcType,
+		"code":   status.Code(err).String(),
+	}
+	c.grpcRequests.With(labels).Inc()
+	c.grpcDuration.With(labels).Observe(time.Since(start).Seconds())
+}
+
+// UnaryServerInterceptor records GRPC unary request metrics labeled by the full method name and code.
+func UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	observeGrpc(info.FullMethod, grpcTypeUnary, start, err)
+	return resp, err
+}
+
+// StreamServerInterceptor records GRPC stream request metrics labeled by the full method name and code.
+func StreamServerInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, stream)
+	observeGrpc(info.FullMethod, grpcTypeStream, start, err)
+	return err
+}
+
+// TimeQuery starts timing a database query of the said store operation, returning a function that
+// records the latency when called, e.g. defer metrics.TimeQuery("get")().
+func TimeQuery(operation string) func() {
+	c := get()
+	if c == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		c.queryDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	}
+}
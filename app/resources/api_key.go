@@ -0,0 +1,128 @@
+package resources
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/bodenr/vehicle-api/db"
+	"github.com/bodenr/vehicle-api/log"
+	"github.com/bodenr/vehicle-api/metrics"
+	"github.com/bodenr/vehicle-api/svr"
+	"github.com/bodenr/vehicle-api/tracing"
+)
+
+// StoredAPIKey implements the APIKeyStore interface for API keys.
+type StoredAPIKey struct{}
+
+// NB: only the hash of keys is stored so they can't be recovered from the database
+var apiKeySchema = `
+CREATE TABLE IF NOT EXISTS api_keys (
+	id VARCHAR(64) NOT NULL PRIMARY KEY,
+	name VARCHAR(64) NOT NULL,
+	prefix VARCHAR(16) NOT NULL,
+	key_hash CHAR(64) UNIQUE NOT NULL,
+	created_at bigint NOT NULL,
+	expires_at bigint NOT NULL DEFAULT 0,
+	last_used_at bigint NOT NULL DEFAULT 0,
+	revoked_at bigint NOT NULL DEFAULT 0
+);
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS roles TEXT NOT NULL DEFAULT '';
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT '';
+`
+
+// CreateSchema creates the database table schema for API keys.
+func (k StoredAPIKey) CreateSchema() error {
+	if _, err := db.GetDB().Exec(apiKeySchema); err != nil {
+		return fmt.Errorf("Failed to create the API key schema: %w", err)
+	}
+	return nil
+}
+
+// CreateKey stores a new API key.
+func (k StoredAPIKey) CreateKey(ctx context.Context, key *svr.APIKey) *svr.StoreError {
+	defer metrics.TimeQuery("create_key")()
+	const query = `INSERT INTO api_keys (id, name, prefix, key_hash, roles, tenant_id, created_at,
+		expires_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8)`
+	ctx, span := tracing.StartQuery(ctx, "create_key", query)
+	defer span.End()
+	_, err := db.GetDB().ExecContext(ctx, query, key.ID, key.Name, key.Prefix, key.Hash,
+		key.Roles, key.Tenant, key.CreatedAt, key.ExpiresAt)
+	if err != nil {
+		log.FromContext(ctx).Err(err).Msg("Database error creating API key")
+		return dbError(ctx, err)
+	}
+	return nil
+}
+
+// ListKeys lists all API keys.
+func (k StoredAPIKey) ListKeys(ctx context.Context) ([]svr.APIKey, *svr.StoreError) {
+	defer metrics.TimeQuery("list_keys")()
+	const query = "SELECT * FROM api_keys ORDER BY created_at"
+	ctx, span := tracing.StartQuery(ctx, "list_keys", query)
+	defer span.End()
+	keys := make([]svr.APIKey, 0)
+	if err := db.GetDB().SelectContext(ctx, &keys, query); err != nil {
+		log.FromContext(ctx).Err(err).Msg("Database error listing API keys")
+		return keys, dbError(ctx, err)
+	}
+	return keys, nil
+}
+
+// FindKey finds the API key with the said hash.
+func (k StoredAPIKey) FindKey(ctx context.Context, hash string) (*svr.APIKey, *svr.StoreError) {
+	defer metrics.TimeQuery("find_key")()
+	const query = "SELECT * FROM api_keys WHERE key_hash=$1"
+	ctx, span := tracing.StartQuery(ctx, "find_key", query)
+	defer span.End()
+	key := svr.APIKey{}
+	if err := db.GetDB().GetContext(ctx, &key, query, hash); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &svr.StoreError{
+				Error:      fmt.Errorf("API key doesn't exist"),
+				StatusCode: http.StatusNotFound,
+			}
+		}
+		log.FromContext(ctx).Err(err).Msg("Database error finding API key")
+		return nil, dbError(ctx, err)
+	}
+	return &key, nil
+}
+
+// RevokeKey revokes the API key with the said ID unless already revoked.
+func (k StoredAPIKey) RevokeKey(ctx context.Context, id string, revokedAt int64) *svr.StoreError {
+	defer metrics.TimeQuery("revoke_key")()
+	const query = "UPDATE api_keys SET revoked_at=$1 WHERE id=$2 AND revoked_at=0"
+	ctx, span := tracing.StartQuery(ctx, "revoke_key", query)
+	defer span.End()
+	result, err := db.GetDB().ExecContext(ctx, query, revokedAt, id)
+	if err != nil {
+		log.FromContext(ctx).Err(err).Str(log.KeyID, id).Msg("Database error revoking API key")
+		return dbError(ctx, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		log.FromContext(ctx).Err(err).Msg("Error revoking API key")
+		return dbError(ctx, err)
+	}
+	if affected == 0 {
+		return &svr.StoreError{
+			Error:      fmt.Errorf("API key %s doesn't exist or is already revoked", id),
+			StatusCode: http.StatusNotFound,
+		}
+	}
+	return nil
+}
+
+// TouchKey updates the last used time of the API key with the said ID.
+func (k StoredAPIKey) TouchKey(ctx context.Context, id string, usedAt int64) *svr.StoreError {
+	defer metrics.TimeQuery("touch_key")()
+	const query = "UPDATE api_keys SET last_used_at=$1 WHERE id=$2 AND last_used_at<$1"
+	ctx, span := tracing.StartQuery(ctx, "touch_key", query)
+	defer span.End()
+	if _, err := db.GetDB().ExecContext(ctx, query, usedAt, id); err != nil {
+		return dbError(ctx, err)
+	}
+	return nil
+}
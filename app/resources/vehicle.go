@@ -2,25 +2,41 @@
 package resources
 
 import (
+	"context"
 	"crypto/md5"
 	"database/sql"
 	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
 
 	"github.com/bodenr/vehicle-api/db"
 	"github.com/bodenr/vehicle-api/log"
+	"github.com/bodenr/vehicle-api/metrics"
 	"github.com/bodenr/vehicle-api/svr"
 	"github.com/bodenr/vehicle-api/svr/proto"
+	"github.com/bodenr/vehicle-api/tracing"
 	"github.com/bodenr/vehicle-api/util"
-	protobuf "github.com/golang/protobuf/proto"
+	"github.com/bodenr/vehicle-api/validation"
 )
 
 // StoredVehicle implements the StoredResource interface for vehicle resources.
-type StoredVehicle struct{}
+type StoredVehicle struct {
+	// Colors is the vocabulary of allowed exterior and interior colors; any color is allowed if empty.
+	Colors []string
+
+	// RowLevelSecurity isolates tenants using Postgres row level security in addition to filtering
+	// queries by tenant.
+	RowLevelSecurity bool
+}
+
+// maxColumnLength is the length of the VARCHAR columns in the vehicles table.
+const maxColumnLength = 64
 
 // TODO: make query checking more generic
 var allowedQueryParams = map[string]bool{
@@ -32,18 +48,98 @@ var allowedQueryParams = map[string]bool{
 }
 
 // TODO: move to sql file
+// NB: the schema is idempotent so columns added after the table was created are applied on start;
+// vehicles stored before tenants were introduced belong to the svr.DefaultTenant
 var schema = `
-CREATE TABLE vehicles (
-	vin VARCHAR(64) UNIQUE NOT NULL PRIMARY KEY,
+CREATE TABLE IF NOT EXISTS vehicles (
+	tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
+	vin VARCHAR(64) NOT NULL,
 	make VARCHAR(64) NOT NULL,
 	model VARCHAR(64) NOT NULL,
 	year integer NOT NULL,
 	exterior_color VARCHAR(64) NOT NULL,
 	interior_color VARCHAR(64) NOT NULL,
-	updated_at bigint NOT NULL
+	updated_at bigint NOT NULL,
+	CONSTRAINT vehicles_tenant_pkey PRIMARY KEY (tenant_id, vin)
 );
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS created_at bigint NOT NULL DEFAULT 0;
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS created_by VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS updated_by VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+UPDATE vehicles SET created_at = updated_at WHERE created_at = 0;
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'vehicles_tenant_pkey') THEN
+		ALTER TABLE vehicles DROP CONSTRAINT IF EXISTS vehicles_vin_key;
+		ALTER TABLE vehicles DROP CONSTRAINT IF EXISTS vehicles_pkey;
+		ALTER TABLE vehicles ADD CONSTRAINT vehicles_tenant_pkey PRIMARY KEY (tenant_id, vin);
+	END IF;
+END $$;
+`
+
+// rowLevelSecuritySchema only exposes the rows of the tenant set by db.WithTenant; FORCE applies
+// the policy to the table owner too, i.e. the app user.
+var rowLevelSecuritySchema = `
+ALTER TABLE vehicles ENABLE ROW LEVEL SECURITY;
+ALTER TABLE vehicles FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS vehicles_tenant_isolation ON vehicles;
+CREATE POLICY vehicles_tenant_isolation ON vehicles
+	USING (tenant_id = current_setting('` + db.TenantSetting + `', true))
+	WITH CHECK (tenant_id = current_setting('` + db.TenantSetting + `', true));
+`
+
+// noRowLevelSecuritySchema reverts the rowLevelSecuritySchema.
+var noRowLevelSecuritySchema = `
+DROP POLICY IF EXISTS vehicles_tenant_isolation ON vehicles;
+ALTER TABLE vehicles NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE vehicles DISABLE ROW LEVEL SECURITY;
 `
 
+// vehicleColumns are the columns of vehicles as selected into a proto.Vehicle.
+const vehicleColumns = `vin, make, model, year, exterior_color, interior_color, created_at,
+	created_by, updated_at, updated_by, version`
+
+// anonymousUser is recorded as the creator and updater of vehicles for unauthenticated requests.
+const anonymousUser = "anonymous"
+
+// actor returns the subject of the authenticated principal of the context, recorded as the creator
+// or updater of vehicles, or anonymousUser if unauthenticated.
+func actor(ctx context.Context) string {
+	principal := svr.PrincipalFromContext(ctx)
+	if principal == nil || principal.Subject == "" {
+		return anonymousUser
+	}
+	subject := []rune(principal.Subject)
+	if len(subject) > maxColumnLength {
+		subject = subject[:maxColumnLength]
+	}
+	return string(subject)
+}
+
+// requestTenant returns the tenant of the context; requests without a tenant are refused rather than
+// risking access to the vehicles of all tenants.
+func requestTenant(ctx context.Context) (string, *svr.StoreError) {
+	tenant := svr.TenantFromContext(ctx)
+	if tenant == "" {
+		log.FromContext(ctx).Error().Msg("Vehicle accessed without a tenant")
+		return "", &svr.StoreError{
+			Error:      svr.ErrMissingTenant,
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+	return tenant, nil
+}
+
+// scoped runs fn with the database, within a transaction scoped to the tenant when using row level
+// security.
+func (v StoredVehicle) scoped(ctx context.Context, tenant string, fn func(sqlx.ExtContext) error) error {
+	if !v.RowLevelSecurity {
+		return fn(db.GetDB())
+	}
+	return db.WithTenant(ctx, tenant, fn)
+}
+
 func vehiclesToInterfaces(vehicles []proto.Vehicle) []interface{} {
 	// https://golang.org/doc/faq#convert_slice_of_interface
 	interfaces := make([]interface{}, len(vehicles))
@@ -53,14 +149,52 @@ func vehiclesToInterfaces(vehicles []proto.Vehicle) []interface{} {
 	return interfaces
 }
 
-// CreateSchema creates the database table schema for vehicles.
-func (v StoredVehicle) CreateSchema() {
-	db.GetDB().MustExec(schema)
+// dbError creates a StoreError for the said database error, recording it on the query span;
+// transient errors are flagged as unavailable so clients know the request can be retried.
+func dbError(ctx context.Context, err error) *svr.StoreError {
+	tracing.RecordError(ctx, err)
+	code := http.StatusInternalServerError
+	if db.IsTransientError(err) {
+		code = http.StatusServiceUnavailable
+	}
+	return &svr.StoreError{
+		Error:      err,
+		StatusCode: code,
+	}
+}
+
+// vehicleNotFound returns the StoreError of a vehicle that doesn't exist; NB: the VIN is left out
+// of the message as error messages are logged, where only fields like vin can be redacted.
+func vehicleNotFound() *svr.StoreError {
+	return &svr.StoreError{
+		Error:      fmt.Errorf("Vehicle doesn't exist"),
+		StatusCode: http.StatusNotFound,
+	}
+}
+
+// Name returns the name of vehicle resources.
+func (v StoredVehicle) Name() string {
+	return "vehicles"
+}
+
+// CreateSchema creates the database table schema for vehicles, applying the row level security
+// policy if enabled.
+func (v StoredVehicle) CreateSchema() error {
+	if _, err := db.GetDB().Exec(schema); err != nil {
+		return fmt.Errorf("Failed to create the vehicle schema: %w", err)
+	}
+	security := noRowLevelSecuritySchema
+	if v.RowLevelSecurity {
+		security = rowLevelSecuritySchema
+	}
+	if _, err := db.GetDB().Exec(security); err != nil {
+		return fmt.Errorf("Failed to apply the vehicle row level security schema: %w", err)
+	}
+	return nil
 }
 
 // BindRoutes bind the vehicle routes to a router.
-func (v StoredVehicle) BindRoutes(router *mux.Router) {
-	handler := svr.NewRestfulResource(StoredVehicle{})
+func (v StoredVehicle) BindRoutes(router *mux.Router, handler svr.RestfulHandler) {
 	router.HandleFunc("/vehicles", handler.List).Methods(http.MethodGet)
 	router.HandleFunc("/vehicles/{vin}", handler.Delete).Methods(http.MethodDelete)
 	router.HandleFunc("/vehicles/{vin}", handler.Get).Methods(http.MethodGet)
@@ -68,165 +202,239 @@ func (v StoredVehicle) BindRoutes(router *mux.Router) {
 	router.HandleFunc("/vehicles", handler.Create).Methods(http.MethodPost)
 }
 
-// Validate validates the said vehicle struct.
+// Validate validates the said vehicle struct collecting all violations.
 func (v StoredVehicle) Validate(resource interface{}, httpMethod string) error {
 	vehicle := resource.(proto.Vehicle)
-	if vehicle.Vin == "" && httpMethod != http.MethodPut {
-		return fmt.Errorf("A vin is required")
-	}
-	if vehicle.Make == "" {
-		return fmt.Errorf("A make is required")
-	}
-	if vehicle.Model == "" {
-		return fmt.Errorf("A model is required")
-	}
-	if vehicle.Year == 0 {
-		return fmt.Errorf("A year is required")
+	validator := validation.New()
+	if httpMethod != http.MethodPut {
+		validator.Field("vin", vehicle.Vin, validation.Required(), validation.MaxLength(maxColumnLength))
+	} else {
+		validator.Field("vin", vehicle.Vin, validation.MaxLength(maxColumnLength))
 	}
-	if vehicle.ExteriorColor == "" {
-		return fmt.Errorf("An exterior_color is required")
-	}
-	if vehicle.InteriorColor == "" {
-		return fmt.Errorf("An interior_color is required")
-	}
-	return nil
+	validator.Field("make", vehicle.Make, validation.Required(), validation.MaxLength(maxColumnLength))
+	validator.Field("model", vehicle.Model, validation.Required(), validation.MaxLength(maxColumnLength))
+	validator.Field("year", vehicle.Year, validation.Required(), validation.ModelYear())
+	validator.Field("exterior_color", vehicle.ExteriorColor, validation.Required(),
+		validation.MaxLength(maxColumnLength), validation.OneOf(v.Colors...))
+	validator.Field("interior_color", vehicle.InteriorColor, validation.Required(),
+		validation.MaxLength(maxColumnLength), validation.OneOf(v.Colors...))
+	return validator.Err()
 }
 
 // Unmarshal converts bytes into a vehicle using the said content type.
 func (v StoredVehicle) Unmarshal(contentType string, resource []byte) (interface{}, error) {
 	vehicle := proto.Vehicle{}
-	if contentType == svr.ContentAppProtobuf {
-		err := protobuf.Unmarshal(resource, &vehicle)
-		return vehicle, err
-	}
 	err := svr.Unmarshal(contentType, resource, &vehicle)
 	return vehicle, err
 }
 
 // Marshal converts a vehicle into bytes for the said content type.
 func (v StoredVehicle) Marshal(contentType string, resource interface{}) ([]byte, error) {
-	if contentType == svr.ContentAppProtobuf {
-		v := resource.(proto.Vehicle)
-		return protobuf.Marshal(&v)
-	}
 	return svr.Marshal(contentType, resource)
 }
 
+// Collection wraps the said vehicles into a VehicleList.
+func (v StoredVehicle) Collection(resources []interface{}) interface{} {
+	list := &proto.VehicleList{
+		Vehicles: make([]*proto.Vehicle, len(resources)),
+	}
+	for i, resource := range resources {
+		vehicle := resource.(proto.Vehicle)
+		list.Vehicles[i] = &vehicle
+	}
+	return list
+}
+
 // Search searches the database for vehicles using the said query params.
-func (v StoredVehicle) Search(queryParams url.Values) ([]interface{}, *svr.StoreError) {
+func (v StoredVehicle) Search(ctx context.Context, queryParams url.Values) ([]interface{}, *svr.StoreError) {
+	defer metrics.TimeQuery("search")()
 	vehicles := make([]proto.Vehicle, 0)
-	statement := "SELECT * FROM vehicles WHERE"
-	var inStatements []string
+	tenant, sErr := requestTenant(ctx)
+	if sErr != nil {
+		return vehiclesToInterfaces(vehicles), sErr
+	}
 
-	for col, vals := range queryParams {
-		_, exists := allowedQueryParams[col]
-		if !exists {
+	// NB: columns are sorted so searches on the same columns use the same statement
+	columns := make([]string, 0, len(queryParams))
+	for col := range queryParams {
+		if _, exists := allowedQueryParams[col]; !exists {
 			return vehiclesToInterfaces(vehicles), &svr.StoreError{
-				Error:      fmt.Errorf("Invalid query param: %s", col),
+				Error:      validation.NewError(col, validation.RuleAllowed, fmt.Sprintf("Invalid query param: %s", col)),
 				StatusCode: http.StatusBadRequest,
 			}
 		}
-		// TODO: rework SQL to prevent injection vector
-		stm := "%s IN ('%s')"
-		if col == "year" {
-			stm = "%s IN (%s)"
+		columns = append(columns, col)
+	}
+	sort.Strings(columns)
+
+	// NB: values are bound as parameters so they're never part of the logged or traced statement
+	args := []interface{}{tenant}
+	conditions := []string{"tenant_id=$1"}
+	for _, col := range columns {
+		placeholders := make([]string, len(queryParams[col]))
+		for i, val := range queryParams[col] {
+			if _, err := strconv.Atoi(val); col == "year" && err != nil {
+				return vehiclesToInterfaces(vehicles), &svr.StoreError{
+					Error:      validation.NewError(col, validation.RuleRange, fmt.Sprintf("Invalid year: %s", val)),
+					StatusCode: http.StatusBadRequest,
+				}
+			}
+			args = append(args, val)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
 		}
-		s := fmt.Sprintf(stm, col, strings.Join(vals[:], ","))
-		inStatements = append(inStatements, s)
+		conditions = append(conditions, fmt.Sprintf("%s IN (%s)", col, strings.Join(placeholders, ",")))
 	}
-	statement = fmt.Sprintf("%s %s", statement, strings.Join(inStatements[:], " AND "))
-	log.Log.Debug().Str(log.Query, statement).Msg("Search query")
+	statement := fmt.Sprintf("SELECT %s FROM vehicles WHERE %s", vehicleColumns, strings.Join(conditions, " AND "))
+	ctx, span := tracing.StartQuery(ctx, "search", statement)
+	defer span.End()
+	log.FromContext(ctx).Debug().Str(log.Query, statement).Msg("Search query")
 
-	store := db.GetDB()
-	err := store.Select(&vehicles, statement)
+	err := v.scoped(ctx, tenant, func(store sqlx.ExtContext) error {
+		return sqlx.SelectContext(ctx, store, &vehicles, statement, args...)
+	})
 	if err != nil {
-		log.Log.Err(err).Msg("Database error listing vehicles")
-		return vehiclesToInterfaces(vehicles), &svr.StoreError{
-			Error:      err,
-			StatusCode: http.StatusInternalServerError,
-		}
+		log.FromContext(ctx).Err(err).Msg("Database error listing vehicles")
+		return vehiclesToInterfaces(vehicles), dbError(ctx, err)
 	}
 	return vehiclesToInterfaces(vehicles), nil
 }
 
-// List returns all vehicles in the database.
-func (v StoredVehicle) List() ([]interface{}, *svr.StoreError) {
+// List returns all vehicles of the tenant in the database.
+func (v StoredVehicle) List(ctx context.Context) ([]interface{}, *svr.StoreError) {
+	defer metrics.TimeQuery("list")()
+	const query = "SELECT " + vehicleColumns + " FROM vehicles WHERE tenant_id=$1"
+	ctx, span := tracing.StartQuery(ctx, "list", query)
+	defer span.End()
 	vehicles := make([]proto.Vehicle, 0)
-	store := db.GetDB()
-	err := store.Select(&vehicles, "SELECT * FROM vehicles")
+	tenant, sErr := requestTenant(ctx)
+	if sErr != nil {
+		return vehiclesToInterfaces(vehicles), sErr
+	}
+	err := v.scoped(ctx, tenant, func(store sqlx.ExtContext) error {
+		return sqlx.SelectContext(ctx, store, &vehicles, query, tenant)
+	})
 	if err != nil {
-		log.Log.Err(err).Msg("Database error listing vehicles")
-		return vehiclesToInterfaces(vehicles), &svr.StoreError{
-			Error:      err,
-			StatusCode: http.StatusInternalServerError,
-		}
+		log.FromContext(ctx).Err(err).Msg("Database error listing vehicles")
+		return vehiclesToInterfaces(vehicles), dbError(ctx, err)
 	}
 	return vehiclesToInterfaces(vehicles), nil
 }
 
 // Get returns a specific vehicles as per the request vars if it exists.
-func (v StoredVehicle) Get(requestVars svr.RequestVars) (interface{}, *svr.StoreError) {
+func (v StoredVehicle) Get(ctx context.Context, requestVars svr.RequestVars) (interface{}, *svr.StoreError) {
+	defer metrics.TimeQuery("get")()
+	const query = "SELECT " + vehicleColumns + " FROM vehicles WHERE tenant_id=$1 AND vin=$2"
+	ctx, span := tracing.StartQuery(ctx, "get", query)
+	defer span.End()
 	vehicle := proto.Vehicle{}
 	vin := requestVars["vin"]
-	store := db.GetDB()
-	err := store.Get(&vehicle, "SELECT * FROM vehicles WHERE vin=$1", vin)
+	tenant, sErr := requestTenant(ctx)
+	if sErr != nil {
+		return vehicle, sErr
+	}
+	err := v.scoped(ctx, tenant, func(store sqlx.ExtContext) error {
+		return sqlx.GetContext(ctx, store, &vehicle, query, tenant, vin)
+	})
 	if err != nil {
 		// TODO: refactor DB common logic
 		if err == sql.ErrNoRows {
-			return vehicle, &svr.StoreError{
-				Error:      err,
-				StatusCode: http.StatusNotFound,
-			}
-		}
-		log.Log.Err(err).Str(log.VIN, vin).Msg("Database error getting vehicle")
-		return vehicle, &svr.StoreError{
-			Error:      err,
-			StatusCode: http.StatusInternalServerError,
+			return vehicle, vehicleNotFound()
 		}
+		log.FromContext(ctx).Err(err).Str(log.VIN, vin).Msg("Database error getting vehicle")
+		return vehicle, dbError(ctx, err)
 	}
 	return vehicle, nil
 }
 
-// Delete deletes a vehicle as specified by the request vars.
-func (v StoredVehicle) Delete(requestVars svr.RequestVars) *svr.StoreError {
+// Delete deletes a vehicle as specified by the request vars, if it still has the ETag when given.
+func (v StoredVehicle) Delete(ctx context.Context, requestVars svr.RequestVars, etag string) *svr.StoreError {
+	defer metrics.TimeQuery("delete")()
 	vin := requestVars["vin"]
-	store := db.GetDB()
-	result, err := store.Exec("DELETE FROM vehicles WHERE vin=$1", vin)
-	if err != nil {
-		log.Log.Err(err).Str(log.VIN, vin).Msg("Database error deleting vehicle")
-		return &svr.StoreError{
-			Error:      err,
-			StatusCode: http.StatusInternalServerError,
-		}
+	query := "DELETE FROM vehicles WHERE tenant_id=$1 AND vin=$2"
+	if etag != "" {
+		query += " AND version=$3"
 	}
-	affected, err := result.RowsAffected()
-	if err != nil {
-		log.Log.Err(err).Msg("Error deleting vehicle")
-		return &svr.StoreError{
-			Error:      err,
-			StatusCode: http.StatusInternalServerError,
+	ctx, span := tracing.StartQuery(ctx, "delete", query)
+	defer span.End()
+	tenant, sErr := requestTenant(ctx)
+	if sErr != nil {
+		return sErr
+	}
+	args := []interface{}{tenant, vin}
+	if etag != "" {
+		args = append(args, etagVersion(vin, etag))
+	}
+	var affected int64
+	err := v.scoped(ctx, tenant, func(store sqlx.ExtContext) error {
+		result, err := store.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
 		}
+		affected, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		log.FromContext(ctx).Err(err).Str(log.VIN, vin).Msg("Database error deleting vehicle")
+		return dbError(ctx, err)
 	}
 	if affected == 0 {
-		return &svr.StoreError{
-			Error:      fmt.Errorf("Vehicle with VIN %s doesn't exist", vin),
-			StatusCode: http.StatusNotFound,
-		}
+		return v.notWritten(ctx, tenant, vin, etag)
 	}
 	return nil
 }
 
+// notWritten returns the StoreError for a write of the vehicle that affected no rows; a 412 if the
+// vehicle exists, i.e. no longer has the ETag, otherwise a 404.
+func (v StoredVehicle) notWritten(ctx context.Context, tenant, vin, etag string) *svr.StoreError {
+	notFound := vehicleNotFound()
+	if etag == "" {
+		return notFound
+	}
+	const query = "SELECT EXISTS (SELECT 1 FROM vehicles WHERE tenant_id=$1 AND vin=$2)"
+	var exists bool
+	err := v.scoped(ctx, tenant, func(store sqlx.ExtContext) error {
+		return sqlx.GetContext(ctx, store, &exists, query, tenant, vin)
+	})
+	if err != nil {
+		log.FromContext(ctx).Err(err).Str(log.VIN, vin).Msg("Database error getting vehicle")
+		return dbError(ctx, err)
+	}
+	if !exists {
+		return notFound
+	}
+	return &svr.StoreError{
+		Error:      svr.ErrETagMismatch,
+		StatusCode: http.StatusPreconditionFailed,
+	}
+}
+
 // Create creates a vehicle.
-func (v StoredVehicle) Create(resource interface{}) (interface{}, *svr.StoreError) {
+func (v StoredVehicle) Create(ctx context.Context, resource interface{}) (interface{}, *svr.StoreError) {
+	defer metrics.TimeQuery("create")()
+	const query = `INSERT INTO vehicles (tenant_id, vin, make, model, year, exterior_color,
+		interior_color, created_at, created_by, updated_at, updated_by, version)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+	ctx, span := tracing.StartQuery(ctx, "create", query)
+	defer span.End()
 	vehicle := resource.(proto.Vehicle)
-	store := db.GetDB()
+	tenant, sErr := requestTenant(ctx)
+	if sErr != nil {
+		return nil, sErr
+	}
 	ts := util.TimeMillis()
-	_, err := store.Exec(`INSERT INTO vehicles (vin, make, model, year, exterior_color, 
-		interior_color, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7)`,
-		vehicle.Vin, vehicle.Make, vehicle.Model, vehicle.Year, vehicle.ExteriorColor,
-		vehicle.InteriorColor, ts)
+	vehicle.CreatedAt = ts
+	vehicle.CreatedBy = actor(ctx)
+	vehicle.UpdatedAt = ts
+	vehicle.UpdatedBy = vehicle.CreatedBy
+	vehicle.Version = 1
+	err := v.scoped(ctx, tenant, func(store sqlx.ExtContext) error {
+		_, err := store.ExecContext(ctx, query, tenant, vehicle.Vin, vehicle.Make, vehicle.Model,
+			vehicle.Year, vehicle.ExteriorColor, vehicle.InteriorColor, vehicle.CreatedAt,
+			vehicle.CreatedBy, vehicle.UpdatedAt, vehicle.UpdatedBy, vehicle.Version)
+		return err
+	})
 	if err != nil {
-		log.Log.Err(err).Msg("Database error creating vehicle")
+		log.FromContext(ctx).Err(err).Msg("Database error creating vehicle")
 
 		// TODO: find a better way to detect db specific errors
 		if strings.Contains(err.Error(), "duplicate key value") {
@@ -236,59 +444,77 @@ func (v StoredVehicle) Create(resource interface{}) (interface{}, *svr.StoreErro
 			}
 		}
 
-		return nil, &svr.StoreError{
-			Error:      err,
-			StatusCode: http.StatusInternalServerError,
-		}
+		return nil, dbError(ctx, err)
 	}
-	vehicle.UpdatedAt = ts
 	return vehicle, nil
 }
 
-// Update updates an existing vehicle.
-func (v StoredVehicle) Update(resource interface{}, requestVars svr.RequestVars) (interface{}, *svr.StoreError) {
+// Update updates an existing vehicle, if it still has the ETag when given.
+func (v StoredVehicle) Update(ctx context.Context, resource interface{},
+	requestVars svr.RequestVars, etag string) (interface{}, *svr.StoreError) {
+
+	defer metrics.TimeQuery("update")()
 	vehicle := resource.(proto.Vehicle)
 	vin := requestVars["vin"]
-	store := db.GetDB()
+	query := `UPDATE vehicles SET make=$1, model=$2, year=$3, exterior_color=$4,
+		interior_color=$5, updated_at=$6, updated_by=$7, version=version+1
+		WHERE tenant_id=$8 AND vin=$9`
+	if etag != "" {
+		query += " AND version=$10"
+	}
+	query += " RETURNING " + vehicleColumns
+	ctx, span := tracing.StartQuery(ctx, "update", query)
+	defer span.End()
+	tenant, sErr := requestTenant(ctx)
+	if sErr != nil {
+		return nil, sErr
+	}
+	args := []interface{}{vehicle.Make, vehicle.Model, vehicle.Year, vehicle.ExteriorColor,
+		vehicle.InteriorColor, util.TimeMillis(), actor(ctx), tenant, vin}
+	if etag != "" {
+		args = append(args, etagVersion(vin, etag))
+	}
 
-	ts := util.TimeMillis()
-	_, err := store.Exec("UPDATE vehicles SET make=$1, model=$2, year=$3, exterior_color=$4, interior_color=$5, updated_at=$6 WHERE vin=$7",
-		vehicle.Make, vehicle.Model, vehicle.Year, vehicle.ExteriorColor, vehicle.InteriorColor, ts, vin)
+	stored := proto.Vehicle{}
+	err := v.scoped(ctx, tenant, func(store sqlx.ExtContext) error {
+		return sqlx.GetContext(ctx, store, &stored, query, args...)
+	})
 	if err != nil {
-		log.Log.Err(err).Msg("Database error updating vehicle")
-		return nil, &svr.StoreError{
-			Error:      err,
-			StatusCode: http.StatusInternalServerError,
+		if err == sql.ErrNoRows {
+			return nil, v.notWritten(ctx, tenant, vin, etag)
 		}
+		log.FromContext(ctx).Err(err).Msg("Database error updating vehicle")
+		return nil, dbError(ctx, err)
 	}
-	vehicle.Vin = vin
-	vehicle.UpdatedAt = ts
-	return vehicle, nil
+	return stored, nil
 }
 
 // GetETag builds an eTag by finding the vehicle in the request vars.
-func (v StoredVehicle) GetETag(requestVars svr.RequestVars) (string, *svr.StoreError) {
+func (v StoredVehicle) GetETag(ctx context.Context, requestVars svr.RequestVars) (string, *svr.StoreError) {
+	defer metrics.TimeQuery("get_etag")()
 	// TODO: refactor interface to return etag on Get/Update/Create
+	const query = "SELECT version FROM vehicles WHERE tenant_id=$1 AND vin=$2"
+	ctx, span := tracing.StartQuery(ctx, "get_etag", query)
+	defer span.End()
 	vehicle := proto.Vehicle{}
 	vin := requestVars["vin"]
-	store := db.GetDB()
-	err := store.Get(&vehicle, "SELECT updated_at FROM vehicles WHERE vin=$1", vin)
+	tenant, sErr := requestTenant(ctx)
+	if sErr != nil {
+		return "", sErr
+	}
+	err := v.scoped(ctx, tenant, func(store sqlx.ExtContext) error {
+		return sqlx.GetContext(ctx, store, &vehicle, query, tenant, vin)
+	})
 	if err != nil {
 		// TODO: refactor DB common logic
 		if err == sql.ErrNoRows {
-			return "", &svr.StoreError{
-				Error:      err,
-				StatusCode: http.StatusNotFound,
-			}
-		}
-		log.Log.Err(err).Str(log.VIN, vin).Msg("Database error getting vehicle")
-		return "", &svr.StoreError{
-			Error:      err,
-			StatusCode: http.StatusInternalServerError,
+			return "", vehicleNotFound()
 		}
+		log.FromContext(ctx).Err(err).Str(log.VIN, vin).Msg("Database error getting vehicle")
+		return "", dbError(ctx, err)
 	}
 
-	return buildETag(vin, vehicle.UpdatedAt), nil
+	return buildETag(vin, vehicle.Version), nil
 }
 
 // BuildETag builds and eTag from the given vehicle resource.
@@ -297,13 +523,26 @@ func (v StoredVehicle) BuildETag(resource interface{}) (string, error) {
 	if vehicle.Vin == "" {
 		return "", fmt.Errorf("Vehicle does not contain a VIN")
 	}
-	if vehicle.UpdatedAt == 0 {
-		return "", fmt.Errorf("Vehicle does not contain an Updated at timestamp")
+	if vehicle.Version == 0 {
+		return "", fmt.Errorf("Vehicle does not contain a version")
 	}
-	return buildETag(vehicle.Vin, vehicle.UpdatedAt), nil
+	return buildETag(vehicle.Vin, vehicle.Version), nil
 }
 
-func buildETag(vin string, updatedAt int64) string {
-	data := []byte(fmt.Sprintf("%s.%d", vin, updatedAt))
-	return fmt.Sprintf("%x", md5.Sum(data))
+// buildETag builds an eTag from the vin and version which is incremented on every write; the
+// version prefix lets writes check the eTag in the same statement.
+func buildETag(vin string, version int64) string {
+	data := []byte(fmt.Sprintf("%s.%d", vin, version))
+	return fmt.Sprintf("%d-%x", version, md5.Sum(data))
+}
+
+// etagVersion returns the version of the vehicle with the vin the eTag was built from, or 0, which
+// no vehicle has, if it isn't an eTag of the vehicle.
+func etagVersion(vin, etag string) int64 {
+	prefix := strings.SplitN(etag, "-", 2)[0]
+	version, err := strconv.ParseInt(prefix, 10, 64)
+	if err != nil || version <= 0 || buildETag(vin, version) != etag {
+		return 0
+	}
+	return version
 }
@@ -0,0 +1,273 @@
+package svr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/bodenr/vehicle-api/log"
+	"github.com/bodenr/vehicle-api/svr/proto/admin"
+	"github.com/bodenr/vehicle-api/util"
+	"github.com/bodenr/vehicle-api/validation"
+	"github.com/gorilla/mux"
+	"github.com/rs/xid"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// AdminPathPrefix is the path prefix of the admin endpoints.
+	AdminPathPrefix = "/admin"
+
+	// maxAPIKeyLifetime is the longest lifetime of API keys in seconds.
+	maxAPIKeyLifetime = 10 * 365 * 24 * 60 * 60
+)
+
+// CreateAPIKeyRequest is the body of API key create requests.
+type CreateAPIKeyRequest struct {
+	Name string `json:"name"`
+	// ExpiresIn is the lifetime of the key in seconds; the key doesn't expire if 0.
+	ExpiresIn int64 `json:"expires_in,omitempty"`
+	// Roles are the roles of the key; roles must be defined by the authorization policy.
+	Roles []string `json:"roles,omitempty"`
+	// Tenant is the tenant the key is bound to; it's required.
+	Tenant string `json:"tenant_id,omitempty"`
+}
+
+// CreatedAPIKey is the response to API key create requests; the key is only ever returned here.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// LogLevels is the admin representation of the default log level and the per package overrides.
+type LogLevels struct {
+	Level    string            `json:"level,omitempty"`
+	Packages map[string]string `json:"packages,omitempty"`
+}
+
+// currentLogLevels returns the log levels in effect.
+func currentLogLevels() *LogLevels {
+	levels := &LogLevels{
+		Level:    log.Level().String(),
+		Packages: map[string]string{},
+	}
+	for pkg, level := range log.PackageLevels() {
+		levels.Packages[pkg] = level.String()
+	}
+	return levels
+}
+
+// setLogLevels changes the default level when set and the level of each package, where an empty
+// level removes the package override. Nothing is changed if any of the levels are invalid.
+func setLogLevels(ctx context.Context, levels *LogLevels) error {
+	level := zerolog.NoLevel
+	if levels.Level != "" {
+		parsed, err := log.ParseLevel(levels.Level)
+		if err != nil {
+			return err
+		}
+		level = parsed
+	}
+	packages := make(map[string]zerolog.Level, len(levels.Packages))
+	for pkg, name := range levels.Packages {
+		if pkg == "" {
+			return fmt.Errorf("Package name not set")
+		}
+		if name == "" {
+			packages[pkg] = zerolog.NoLevel
+			continue
+		}
+		parsed, err := log.ParseLevel(name)
+		if err != nil {
+			return fmt.Errorf("Invalid log level of package %s: %v", pkg, err)
+		}
+		packages[pkg] = parsed
+	}
+
+	if level != zerolog.NoLevel {
+		log.SetLevel(level)
+	}
+	for pkg, packageLevel := range packages {
+		if packageLevel == zerolog.NoLevel {
+			log.ClearPackageLevel(pkg)
+		} else {
+			log.SetPackageLevel(pkg, packageLevel)
+		}
+	}
+	// NB: logged as a warning so the change is seen unless the level is above warn
+	log.FromContext(ctx).Warn().
+		Str(log.LogLevel, log.Level().String()).
+		Interface(log.Packages, currentLogLevels().Packages).
+		Msg("Changed log level")
+	return nil
+}
+
+// BindAdminRoutes binds the admin endpoints to the admin subrouter.
+func BindAdminRoutes(router *mux.Router) {
+	router.HandleFunc("/log-level", GetLogLevel).Methods(http.MethodGet)
+	router.HandleFunc("/log-level", SetLogLevel).Methods(http.MethodPut)
+}
+
+// GetLogLevel responds with the log levels in effect.
+func GetLogLevel(writer http.ResponseWriter, request *http.Request) {
+	respondAdmin(writer, request, http.StatusOK, currentLogLevels())
+}
+
+// SetLogLevel changes the log levels as per the json LogLevels request body, responding with the
+// log levels in effect.
+func SetLogLevel(writer http.ResponseWriter, request *http.Request) {
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		respondAdmin(writer, request, http.StatusBadRequest, NewProblem(request, http.StatusBadRequest, err))
+		return
+	}
+	levels := &LogLevels{}
+	if err = json.Unmarshal(body, levels); err != nil {
+		respondAdmin(writer, request, http.StatusBadRequest, NewProblem(request, http.StatusBadRequest, err))
+		return
+	}
+	if err = setLogLevels(request.Context(), levels); err != nil {
+		respondAdmin(writer, request, http.StatusBadRequest, NewProblem(request, http.StatusBadRequest, err))
+		return
+	}
+	respondAdmin(writer, request, http.StatusOK, currentLogLevels())
+}
+
+// BindRoutes binds the API key admin endpoints to the admin subrouter.
+func (auth *Auth) BindRoutes(router *mux.Router) {
+	router.HandleFunc("/api-keys", auth.ListKeys).Methods(http.MethodGet)
+	router.HandleFunc("/api-keys", auth.CreateKey).Methods(http.MethodPost)
+	router.HandleFunc("/api-keys/{id}", auth.RevokeKey).Methods(http.MethodDelete)
+}
+
+// ListKeys responds with all API keys.
+func (auth *Auth) ListKeys(writer http.ResponseWriter, request *http.Request) {
+	keys, sErr := auth.keys.ListKeys(request.Context())
+	if sErr != nil {
+		respondAdmin(writer, request, sErr.StatusCode, NewProblem(request, sErr.StatusCode, sErr.Error))
+		return
+	}
+	respondAdmin(writer, request, http.StatusOK, keys)
+}
+
+// CreateKey creates an API key as per the json CreateAPIKeyRequest body, responding with the key.
+func (auth *Auth) CreateKey(writer http.ResponseWriter, request *http.Request) {
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		respondAdmin(writer, request, http.StatusBadRequest, NewProblem(request, http.StatusBadRequest, err))
+		return
+	}
+	create := &CreateAPIKeyRequest{}
+	if err = json.Unmarshal(body, create); err != nil {
+		respondAdmin(writer, request, http.StatusBadRequest, NewProblem(request, http.StatusBadRequest, err))
+		return
+	}
+	validator := validation.New().
+		Field("name", create.Name, validation.Required(), validation.MaxLength(64)).
+		Field("expires_in", create.ExpiresIn, validation.Range(1, maxAPIKeyLifetime))
+	if len(create.Roles) > 0 && auth.policy == nil {
+		respondAdmin(writer, request, http.StatusBadRequest,
+			NewProblem(request, http.StatusBadRequest, ErrRolesWithoutPolicy))
+		return
+	}
+	for i, role := range create.Roles {
+		create.Roles[i] = normalizeRole(role)
+		validator.Field(fmt.Sprintf("roles[%d]", i), create.Roles[i], validation.Required(),
+			validation.OneOf(auth.policy.RoleNames()...))
+	}
+	validator.Field("tenant_id", create.Tenant, validation.Required(), validation.Format(tenantPattern, tenantFormat))
+	if err = validator.Err(); err != nil {
+		respondAdmin(writer, request, http.StatusBadRequest, NewProblem(request, http.StatusBadRequest, err))
+		return
+	}
+
+	key, err := NewAPIKey()
+	if err != nil {
+		log.FromContext(request.Context()).Err(err).Msg("Error generating API key")
+		respondAdmin(writer, request, http.StatusInternalServerError,
+			NewProblem(request, http.StatusInternalServerError, nil))
+		return
+	}
+	now := util.TimeMillis()
+	apiKey := APIKey{
+		ID:        xid.New().String(),
+		Name:      create.Name,
+		Prefix:    key[:apiKeyDisplayLength],
+		Hash:      HashAPIKey(key),
+		Roles:     create.Roles,
+		Tenant:    create.Tenant,
+		CreatedAt: now,
+	}
+	if create.ExpiresIn > 0 {
+		apiKey.ExpiresAt = now + create.ExpiresIn*1000
+	}
+	if sErr := auth.keys.CreateKey(request.Context(), &apiKey); sErr != nil {
+		respondAdmin(writer, request, sErr.StatusCode, NewProblem(request, sErr.StatusCode, sErr.Error))
+		return
+	}
+	log.FromContext(request.Context()).Info().Str(log.KeyID, apiKey.ID).Msg("Created API key")
+	respondAdmin(writer, request, http.StatusCreated, &CreatedAPIKey{APIKey: apiKey, Key: key})
+}
+
+// RevokeKey revokes the API key of the request path.
+func (auth *Auth) RevokeKey(writer http.ResponseWriter, request *http.Request) {
+	id := mux.Vars(request)["id"]
+	if sErr := auth.keys.RevokeKey(request.Context(), id, util.TimeMillis()); sErr != nil {
+		respondAdmin(writer, request, sErr.StatusCode, NewProblem(request, sErr.StatusCode, sErr.Error))
+		return
+	}
+	log.FromContext(request.Context()).Info().Str(log.KeyID, id).Msg("Revoked API key")
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// respondAdmin writes the admin response as json, or problem json for errors.
+func respondAdmin(writer http.ResponseWriter, request *http.Request, code int, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.FromContext(request.Context()).Err(err).Msg("Error marshalling admin response")
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	contentType := ContentAppJSON
+	if code >= http.StatusBadRequest {
+		contentType = ContentAppProblemJSON
+	}
+	writer.Header().Set("Content-Type", contentType)
+	writer.Header().Set("Cache-Control", "no-store")
+	writer.WriteHeader(code)
+	writer.Write(data)
+}
+
+// GrpcAdminHandler implements the GRPC admin services.
+type GrpcAdminHandler struct {
+	admin.UnimplementedLogAdminServer
+}
+
+// toLogLevelsMessage converts the log levels to their GRPC message.
+func toLogLevelsMessage(levels *LogLevels) *admin.LogLevels {
+	return &admin.LogLevels{
+		Level:    levels.Level,
+		Packages: levels.Packages,
+	}
+}
+
+// GetLogLevel returns the log levels in effect.
+func (handler *GrpcAdminHandler) GetLogLevel(ctx context.Context,
+	request *admin.GetLogLevelRequest) (*admin.LogLevels, error) {
+	return toLogLevelsMessage(currentLogLevels()), nil
+}
+
+// SetLogLevel changes the log levels, returning the log levels in effect.
+func (handler *GrpcAdminHandler) SetLogLevel(ctx context.Context,
+	request *admin.SetLogLevelRequest) (*admin.LogLevels, error) {
+	err := setLogLevels(ctx, &LogLevels{
+		Level:    request.Level,
+		Packages: request.Packages,
+	})
+	if err != nil {
+		return nil, invalidArgumentStatus(err)
+	}
+	return toLogLevelsMessage(currentLogLevels()), nil
+}
@@ -0,0 +1,439 @@
+package svr
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bodenr/vehicle-api/config"
+	"github.com/bodenr/vehicle-api/jwt"
+	"github.com/bodenr/vehicle-api/log"
+	"github.com/bodenr/vehicle-api/util"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/hlog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// AuthMethodAPIKey is the method of principals authenticated with an API key.
+	AuthMethodAPIKey = "api_key"
+
+	// AuthMethodAdminKey is the method of principals authenticated with the admin key.
+	AuthMethodAdminKey = "admin_key"
+
+	// AuthMethodJWT is the method of principals authenticated with a JWT bearer token.
+	AuthMethodJWT = "jwt"
+
+	// APIKeyHeader is the header, and GRPC metadata key, of API keys sent without the
+	// Authorization header.
+	APIKeyHeader = "x-api-key"
+
+	// apiKeyPrefix prefixes generated API keys so they're recognizable, e.g. by secret scanners.
+	apiKeyPrefix = "vk_"
+
+	// apiKeyDisplayLength is the length of the key prefix stored to identify a key.
+	apiKeyDisplayLength = 10
+
+	// DefaultKeyUsageInterval is how often the last used time of an API key is updated by default.
+	DefaultKeyUsageInterval = time.Minute
+
+	// DefaultRolesClaim is the token claim holding the roles of principals by default.
+	DefaultRolesClaim = "roles"
+)
+
+var (
+	// ErrMissingCredentials is the error used when a request doesn't send credentials.
+	ErrMissingCredentials = errors.New("Missing credentials")
+
+	// ErrInvalidCredentials is the error used when the credentials of a request are unknown,
+	// expired or revoked.
+	ErrInvalidCredentials = errors.New("Invalid credentials")
+
+	// nameClaims are the token claims used as the name of principals, in order of preference.
+	nameClaims = []string{"name", "preferred_username", "email"}
+)
+
+// grpcPublicServices are the GRPC services served without authentication.
+var grpcPublicServices = []string{
+	"/grpc.health.v1.Health/",
+	"/grpc.reflection.v1alpha.ServerReflection/",
+}
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	// Subject identifies the caller, e.g. the ID of its API key.
+	Subject string
+	// Name is the display name of the caller, e.g. the name of its API key.
+	Name string
+	// Method is how the caller was authenticated; one of the AuthMethod values.
+	Method string
+	// Roles are the roles of the caller, mapped to permissions by the authorization policy.
+	Roles []string
+	// Tenant is the tenant the caller is bound to; callers without a tenant are denied unless
+	// configured to use the DefaultTenant.
+	Tenant string
+	// Claims are the verified claims of the bearer token of callers authenticated with a JWT.
+	Claims jwt.Claims
+}
+
+// principalKey is the context key of the Principal.
+type principalKey struct{}
+
+// WithPrincipal returns a copy of the context carrying the said principal.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal of the request context or nil if unauthenticated.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
+
+// APIKey is a stored API key; only the hash of the key itself is stored.
+type APIKey struct {
+	ID     string   `json:"id" db:"id"`
+	Name   string   `json:"name" db:"name"`
+	Prefix string   `json:"prefix" db:"prefix"`
+	Hash   string   `json:"-" db:"key_hash"`
+	Roles  RoleList `json:"roles,omitempty" db:"roles"`
+	Tenant string   `json:"tenant_id,omitempty" db:"tenant_id"`
+	// CreatedAt and the other times are in milliseconds; 0 means not set.
+	CreatedAt  int64 `json:"created_at" db:"created_at"`
+	ExpiresAt  int64 `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt int64 `json:"last_used_at,omitempty" db:"last_used_at"`
+	RevokedAt  int64 `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+// RoleList is a list of roles stored as comma separated text.
+type RoleList []string
+
+// Value returns the roles as stored.
+func (roles RoleList) Value() (driver.Value, error) {
+	return strings.Join(roles, ","), nil
+}
+
+// Scan reads the stored roles.
+func (roles *RoleList) Scan(src interface{}) error {
+	var value string
+	switch v := src.(type) {
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	case nil:
+	default:
+		return fmt.Errorf("Unsupported roles type %T", src)
+	}
+	*roles = nil
+	if value != "" {
+		*roles = strings.Split(value, ",")
+	}
+	return nil
+}
+
+// Valid returns true if the key is neither revoked nor expired at the said time in milliseconds.
+func (key *APIKey) Valid(now int64) bool {
+	return key.RevokedAt == 0 && (key.ExpiresAt == 0 || now < key.ExpiresAt)
+}
+
+// APIKeyStore stores API keys.
+type APIKeyStore interface {
+	// CreateSchema creates the datastore schema for API keys.
+	CreateSchema() error
+
+	// CreateKey stores a new API key.
+	CreateKey(ctx context.Context, key *APIKey) *StoreError
+
+	// ListKeys lists all API keys, including revoked and expired keys.
+	ListKeys(ctx context.Context) ([]APIKey, *StoreError)
+
+	// FindKey finds the API key with the said hash.
+	FindKey(ctx context.Context, hash string) (*APIKey, *StoreError)
+
+	// RevokeKey revokes the API key with the said ID.
+	RevokeKey(ctx context.Context, id string, revokedAt int64) *StoreError
+
+	// TouchKey updates the last used time of the API key with the said ID.
+	TouchKey(ctx context.Context, id string, usedAt int64) *StoreError
+}
+
+// NewAPIKey generates a new random API key.
+func NewAPIKey() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// HashAPIKey returns the hash of the API key as stored; keys are random so a fast hash suffices.
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// requestCredentials returns the API key of the x-api-key value, or else the bearer Authorization
+// value.
+func requestCredentials(authorization, apiKey string) string {
+	if apiKey != "" {
+		return apiKey
+	}
+	const bearer = "bearer "
+	if len(authorization) > len(bearer) && strings.EqualFold(authorization[:len(bearer)], bearer) {
+		return strings.TrimSpace(authorization[len(bearer):])
+	}
+	return ""
+}
+
+// Auth authenticates REST and GRPC requests with API keys or JWT bearer tokens.
+type Auth struct {
+	// keys is nil unless API keys are enabled; tokens is nil unless JWTs are enabled.
+	keys          APIKeyStore
+	tokens        *jwt.Verifier
+	rolesClaim    string
+	tenantClaim   string
+	adminKey      string
+	usageInterval time.Duration
+	// policy is nil unless requests are authorized.
+	policy *Policy
+}
+
+// NewAuth creates the Auth for the config and key store or returns nil if authentication is
+// disabled. An authorization policy can only be used along with authentication.
+func NewAuth(conf *config.AuthConfig, keys APIKeyStore) (*Auth, error) {
+	if !conf.Enabled() {
+		if conf.PolicyFile != "" {
+			return nil, fmt.Errorf("Authorization policy requires API key or JWT authentication")
+		}
+		return nil, nil
+	}
+	if conf.AdminKey == "" {
+		log.Log.Warn().Msg("No admin key set, admin endpoints are unavailable")
+	}
+	auth := &Auth{
+		adminKey:      conf.AdminKey,
+		usageInterval: conf.UsageInterval,
+	}
+	if conf.APIKeys {
+		auth.keys = keys
+	}
+	if conf.JWT.Enabled() {
+		verifier, err := jwt.NewVerifier(&conf.JWT)
+		if err != nil {
+			return nil, err
+		}
+		auth.tokens = verifier
+		auth.rolesClaim = conf.JWT.RolesClaim
+		auth.tenantClaim = conf.JWT.TenantClaim
+	}
+	if conf.PolicyFile != "" {
+		policy, err := LoadPolicy(conf.PolicyFile)
+		if err != nil {
+			return nil, err
+		}
+		auth.policy = policy
+		log.Log.Info().Strs(log.Roles, policy.RoleNames()).Msg("Loaded authorization policy")
+	}
+	return auth, nil
+}
+
+// isJWT returns true if the credentials look like a JWT rather than an API key.
+func isJWT(credentials string) bool {
+	return strings.Count(credentials, ".") == 2
+}
+
+// Authenticate returns the principal of the said API key or JWT.
+func (auth *Auth) Authenticate(ctx context.Context, credentials string) (*Principal, *StoreError) {
+	if credentials == "" {
+		return nil, &StoreError{Error: ErrMissingCredentials, StatusCode: http.StatusUnauthorized}
+	}
+	if auth.tokens != nil && isJWT(credentials) {
+		return auth.authenticateToken(ctx, credentials)
+	}
+	if auth.keys == nil {
+		return nil, &StoreError{Error: ErrInvalidCredentials, StatusCode: http.StatusUnauthorized}
+	}
+	return auth.authenticateKey(ctx, credentials)
+}
+
+// authenticateToken returns the principal of the said JWT.
+func (auth *Auth) authenticateToken(ctx context.Context, token string) (*Principal, *StoreError) {
+	claims, err := auth.tokens.Verify(ctx, token)
+	if err != nil {
+		if err == jwt.ErrKeysUnavailable {
+			return nil, &StoreError{Error: err, StatusCode: http.StatusServiceUnavailable}
+		}
+		log.FromContext(ctx).Debug().Err(err).Msg("Rejected bearer token")
+		return nil, &StoreError{Error: err, StatusCode: http.StatusUnauthorized}
+	}
+	if claims.Subject() == "" {
+		return nil, &StoreError{Error: ErrInvalidCredentials, StatusCode: http.StatusUnauthorized}
+	}
+	principal := &Principal{
+		Subject: claims.Subject(),
+		Method:  AuthMethodJWT,
+		Claims:  claims,
+	}
+	if auth.rolesClaim != "" {
+		principal.Roles = claims.Strings(auth.rolesClaim)
+	}
+	if auth.tenantClaim != "" {
+		principal.Tenant = claims.String(auth.tenantClaim)
+	}
+	for _, claim := range nameClaims {
+		if principal.Name = claims.String(claim); principal.Name != "" {
+			break
+		}
+	}
+	return principal, nil
+}
+
+// authenticateKey returns the principal of the said API key.
+func (auth *Auth) authenticateKey(ctx context.Context, key string) (*Principal, *StoreError) {
+	apiKey, sErr := auth.keys.FindKey(ctx, HashAPIKey(key))
+	if sErr != nil {
+		if sErr.StatusCode == http.StatusNotFound {
+			return nil, &StoreError{Error: ErrInvalidCredentials, StatusCode: http.StatusUnauthorized}
+		}
+		return nil, sErr
+	}
+	now := util.TimeMillis()
+	if !apiKey.Valid(now) {
+		return nil, &StoreError{Error: ErrInvalidCredentials, StatusCode: http.StatusUnauthorized}
+	}
+	// NB: the last used time is only updated once per interval to avoid a write on every request
+	if now-apiKey.LastUsedAt >= auth.usageInterval.Milliseconds() {
+		if sErr = auth.keys.TouchKey(ctx, apiKey.ID, now); sErr != nil {
+			log.FromContext(ctx).Warn().Err(sErr.Error).Msg("Failed to update API key last used time")
+		}
+	}
+	return &Principal{
+		Subject: apiKey.ID,
+		Name:    apiKey.Name,
+		Method:  AuthMethodAPIKey,
+		Roles:   apiKey.Roles,
+		Tenant:  apiKey.Tenant,
+	}, nil
+}
+
+// AuthenticateAdmin returns the admin principal if the key is the admin key.
+func (auth *Auth) AuthenticateAdmin(ctx context.Context, key string) (*Principal, *StoreError) {
+	if key == "" {
+		return nil, &StoreError{Error: ErrMissingCredentials, StatusCode: http.StatusUnauthorized}
+	}
+	if auth.adminKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(auth.adminKey)) != 1 {
+		return nil, &StoreError{Error: ErrInvalidCredentials, StatusCode: http.StatusUnauthorized}
+	}
+	return &Principal{
+		Subject: "admin",
+		Name:    "admin",
+		Method:  AuthMethodAdminKey,
+	}, nil
+}
+
+// authenticator authenticates the credentials of a request.
+type authenticator func(ctx context.Context, key string) (*Principal, *StoreError)
+
+// httpHandler is middleware authenticating REST requests with the said authenticator, responding
+// with an error in the said format when authentication fails.
+func (auth *Auth) httpHandler(errorFormat string, authenticate authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			key := requestCredentials(request.Header.Get("Authorization"), request.Header.Get(APIKeyHeader))
+			principal, sErr := authenticate(request.Context(), key)
+			if sErr != nil {
+				if sErr.StatusCode == http.StatusUnauthorized {
+					writer.Header().Set("WWW-Authenticate", `Bearer realm="vehicle-api"`)
+				} else {
+					log.FromContext(request.Context()).Err(sErr.Error).Msg("Error authenticating request")
+				}
+				respondErr(writer, request, errorFormat, sErr.StatusCode, sErr.Error)
+				return
+			}
+			hlog.FromRequest(request).UpdateContext(func(c zerolog.Context) zerolog.Context {
+				return c.Str(log.Principal, principal.Subject)
+			})
+			next.ServeHTTP(writer, request.WithContext(WithPrincipal(request.Context(), principal)))
+		})
+	}
+}
+
+// isPublicGrpcMethod returns true if the GRPC method is served without authentication.
+func isPublicGrpcMethod(fullMethod string) bool {
+	for _, service := range grpcPublicServices {
+		if strings.HasPrefix(fullMethod, service) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAdminGrpcMethod returns true if the GRPC method is of an admin service.
+func isAdminGrpcMethod(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/admin.")
+}
+
+// authenticateGrpc authenticates the GRPC request, using the admin key for admin services,
+// returning the context with the principal.
+func (auth *Auth) authenticateGrpc(ctx context.Context, fullMethod string) (context.Context, error) {
+	if isPublicGrpcMethod(fullMethod) {
+		return ctx, nil
+	}
+	var authorization, apiKey string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			authorization = values[0]
+		}
+		if values := md.Get(APIKeyHeader); len(values) > 0 {
+			apiKey = values[0]
+		}
+	}
+	authenticate := auth.Authenticate
+	if isAdminGrpcMethod(fullMethod) {
+		authenticate = auth.AuthenticateAdmin
+	}
+	principal, sErr := authenticate(ctx, requestCredentials(authorization, apiKey))
+	if sErr != nil {
+		if sErr.StatusCode != http.StatusUnauthorized {
+			log.FromContext(ctx).Err(sErr.Error).Msg("Error authenticating request")
+		}
+		return nil, status.Error(grpcCode(sErr.StatusCode), sErr.Error.Error())
+	}
+	// NB: the request logger is updated in place so the access log includes the principal
+	log.UpdateContext(ctx, func(c zerolog.Context) zerolog.Context {
+		return c.Str(log.Principal, principal.Subject)
+	})
+	return WithPrincipal(ctx, principal), nil
+}
+
+// UnaryServerInterceptor authenticates unary GRPC requests.
+func (auth *Auth) UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := auth.authenticateGrpc(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamServerInterceptor authenticates stream GRPC requests.
+func (auth *Auth) StreamServerInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	ctx, err := auth.authenticateGrpc(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+}
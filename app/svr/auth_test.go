@@ -0,0 +1,87 @@
+package svr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	testAdminKey = "test-admin-key"
+	testAPIKey   = "vk_test-api-key"
+)
+
+// authTestKeys is an APIKeyStore holding only testAPIKey.
+type authTestKeys struct {
+	APIKeyStore
+}
+
+func (keys authTestKeys) FindKey(ctx context.Context, hash string) (*APIKey, *StoreError) {
+	if hash != HashAPIKey(testAPIKey) {
+		return nil, &StoreError{Error: errors.New("Not found"), StatusCode: http.StatusNotFound}
+	}
+	return &APIKey{ID: "key-1", Name: "ci", Tenant: "dealer-a"}, nil
+}
+
+func (keys authTestKeys) TouchKey(context.Context, string, int64) *StoreError {
+	return nil
+}
+
+func TestAuthGrpcAdminMethods(t *testing.T) {
+	auth := &Auth{keys: authTestKeys{}, adminKey: testAdminKey}
+	tests := []struct {
+		name      string
+		method    string
+		md        metadata.MD
+		code      codes.Code
+		principal string
+	}{
+		{"admin without credentials", "/admin.LogAdmin/SetLogLevel", nil, codes.Unauthenticated, ""},
+		{"admin with api key", "/admin.LogAdmin/SetLogLevel",
+			metadata.Pairs(APIKeyHeader, testAPIKey), codes.Unauthenticated, ""},
+		{"admin with bearer api key", "/admin.LogAdmin/GetLogLevel",
+			metadata.Pairs("authorization", "Bearer "+testAPIKey), codes.Unauthenticated, ""},
+		{"admin with wrong key", "/admin.LogAdmin/SetLogLevel",
+			metadata.Pairs(APIKeyHeader, testAdminKey+"x"), codes.Unauthenticated, ""},
+		{"admin with admin key", "/admin.LogAdmin/SetLogLevel",
+			metadata.Pairs(APIKeyHeader, testAdminKey), codes.OK, "admin"},
+		{"admin with bearer admin key", "/admin.LogAdmin/GetLogLevel",
+			metadata.Pairs("authorization", "Bearer "+testAdminKey), codes.OK, "admin"},
+		{"vehicles with admin key", "/vehicle.VehicleStore/GetVehicle",
+			metadata.Pairs(APIKeyHeader, testAdminKey), codes.Unauthenticated, ""},
+		{"vehicles with api key", "/vehicle.VehicleStore/GetVehicle",
+			metadata.Pairs(APIKeyHeader, testAPIKey), codes.OK, "key-1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			if test.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, test.md)
+			}
+			var principal *Principal
+			_, err := auth.UnaryServerInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: test.method},
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					principal = PrincipalFromContext(ctx)
+					return nil, nil
+				})
+			if code := status.Code(err); code != test.code {
+				t.Fatalf("expected code %s, got %s", test.code, code)
+			}
+			if test.code != codes.OK {
+				if principal != nil {
+					t.Fatal("expected the handler not to be called")
+				}
+				return
+			}
+			if principal == nil || principal.Subject != test.principal {
+				t.Fatalf("expected principal %s, got %v", test.principal, principal)
+			}
+		})
+	}
+}
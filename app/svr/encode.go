@@ -1,10 +1,18 @@
 package svr
 
 import (
+	"bytes"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/vmihailenco/msgpack/v5"
+	"sigs.k8s.io/yaml"
 )
 
 const (
@@ -16,8 +24,20 @@ const (
 
 	// ContentAppProtobuf content type for protobuf.
 	ContentAppProtobuf = "application/x-protobuf"
+
+	// ContentAppYAML content type for yaml.
+	ContentAppYAML = "application/yaml"
+
+	// ContentAppMsgpack content type for MessagePack.
+	ContentAppMsgpack = "application/msgpack"
+
+	// ContentAppCBOR content type for CBOR.
+	ContentAppCBOR = "application/cbor"
 )
 
+// NB: the YAML, MessagePack and CBOR encodings key struct fields off their json tags so the
+// generated protobuf types encode with the same field names as the JSON API.
+
 // Encoding provides the means to marshal and unmarshal data.
 type Encoding interface {
 	Marshal(dataType interface{}) ([]byte, error)
@@ -33,6 +53,15 @@ type XMLEncoding struct{}
 // ProtobufEncoding provides Encoding for Protobuf content.
 type ProtobufEncoding struct{}
 
+// YAMLEncoding provides Encoding for YAML content.
+type YAMLEncoding struct{}
+
+// MsgpackEncoding provides Encoding for MessagePack content.
+type MsgpackEncoding struct{}
+
+// CBOREncoding provides Encoding for CBOR content.
+type CBOREncoding struct{}
+
 // Marshal a data object into JSON.
 func (e *JSONEncoding) Marshal(dataType interface{}) ([]byte, error) {
 	return json.Marshal(dataType)
@@ -53,64 +82,122 @@ func (e *XMLEncoding) Unmarshal(data []byte, dataType interface{}) error {
 	return xml.Unmarshal(data, dataType)
 }
 
-// Marshal is not implemented an only provided to signify protobuf content is supported.
+// Marshal a protobuf message into protobuf; struct values whose pointer type is a message are also supported.
 func (e *ProtobufEncoding) Marshal(dataType interface{}) ([]byte, error) {
-	return nil, fmt.Errorf("No generic protobuf marshaller")
+	message, err := toProtoMessage(dataType)
+	if err != nil {
+		return nil, err
+	}
+	return protobuf.Marshal(message)
 }
 
-// Unmarshal is not implemented an only provided to signify protobuf content is supported.
+// Unmarshal protobuf content into a data object which must be a pointer to a protobuf message.
 func (e *ProtobufEncoding) Unmarshal(data []byte, dataType interface{}) error {
-	return fmt.Errorf("No generic protobuf unmarshaller")
+	message, ok := dataType.(protobuf.Message)
+	if !ok {
+		return fmt.Errorf("Type %T is not a protobuf message", dataType)
+	}
+	return protobuf.Unmarshal(data, message)
 }
 
-var encodings = map[string]Encoding{
-	ContentAppJSON: &JSONEncoding{},
-	ContentAppXML:  &XMLEncoding{},
-	// TODO: find a way to support a generic protobuf encoding
-	ContentAppProtobuf: &ProtobufEncoding{},
+// toProtoMessage returns the said data object as a protobuf message. Generated messages implement
+// proto.Message on their pointer type, so struct values are copied into a new pointer.
+func toProtoMessage(dataType interface{}) (protobuf.Message, error) {
+	if message, ok := dataType.(protobuf.Message); ok {
+		return message, nil
+	}
+	value := reflect.ValueOf(dataType)
+	if value.Kind() == reflect.Struct {
+		ptr := reflect.New(value.Type())
+		ptr.Elem().Set(value)
+		if message, ok := ptr.Interface().(protobuf.Message); ok {
+			return message, nil
+		}
+	}
+	return nil, fmt.Errorf("Type %T is not a protobuf message", dataType)
 }
 
-// SupportsEncoding returns if the said encoding type is supported.
+// Marshal a data object into YAML.
+func (e *YAMLEncoding) Marshal(dataType interface{}) ([]byte, error) {
+	return yaml.Marshal(dataType)
+}
+
+// Unmarshal YAML content into a data object.
+func (e *YAMLEncoding) Unmarshal(data []byte, dataType interface{}) error {
+	return yaml.Unmarshal(data, dataType)
+}
+
+// Marshal a data object into MessagePack.
+func (e *MsgpackEncoding) Marshal(dataType interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.SetCustomStructTag("json")
+	if err := encoder.Encode(dataType); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal MessagePack content into a data object.
+func (e *MsgpackEncoding) Unmarshal(data []byte, dataType interface{}) error {
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+	return decoder.Decode(dataType)
+}
+
+// Marshal a data object into CBOR.
+func (e *CBOREncoding) Marshal(dataType interface{}) ([]byte, error) {
+	return cbor.Marshal(dataType)
+}
+
+// Unmarshal CBOR content into a data object.
+func (e *CBOREncoding) Unmarshal(data []byte, dataType interface{}) error {
+	return cbor.Unmarshal(data, dataType)
+}
+
+// SupportsEncoding returns if the said encoding type is supported for requests and responses; error
+// only encodings aren't.
 func SupportsEncoding(encoding string) bool {
-	_, exists := encodings[encoding]
-	return exists
+	reg := registry.lookup(encoding)
+	return reg != nil && !reg.errorOnly
 }
 
 // Unmarshal the said encoding.
 func Unmarshal(encoding string, data []byte, dataType interface{}) error {
-	enc, exists := encodings[encoding]
-	if !exists {
+	reg := registry.lookup(encoding)
+	if reg == nil {
 		return fmt.Errorf("No such encoding: %s", encoding)
 	}
-	return enc.Unmarshal(data, dataType)
+	return reg.encoding.Unmarshal(data, dataType)
 }
 
 // Marshal the said encoding.
 func Marshal(encoding string, dataType interface{}) ([]byte, error) {
-	enc, exists := encodings[encoding]
-	if !exists {
+	reg := registry.lookup(encoding)
+	if reg == nil {
 		return nil, fmt.Errorf("No such encoding: %s", encoding)
 	}
-	return enc.Marshal(dataType)
+	return reg.encoding.Marshal(dataType)
 }
 
 // GetRequestContentType get the content type from the request header by checking it against supported encodings.
+// Media type parameters such as charset are ignored.
 func GetRequestContentType(request *http.Request) string {
-	for _, contentType := range request.Header.Values("Content-Type") {
-		if SupportsEncoding(contentType) {
+	for _, value := range request.Header.Values("Content-Type") {
+		contentType, _, err := ParseMediaType(value)
+		if err == nil && SupportsEncoding(contentType) {
 			return contentType
 		}
 	}
 	return ""
 }
 
-// GetResponseContentType get the content type from the request header by checking it against supported encodings.
+// GetResponseContentType negotiates the response content type using the request Accept header.
+// An empty string is returned if the request accepts none of the supported encodings.
 func GetResponseContentType(request *http.Request) string {
-	// TODO: support weighted encodings, */*, etc..
-	for _, acceptType := range request.Header.Values("Accept") {
-		if SupportsEncoding(acceptType) {
-			return acceptType
-		}
+	accept := request.Header.Values("Accept")
+	if len(accept) > 0 && strings.TrimSpace(strings.Join(accept, "")) != "" {
+		return Negotiate(ParseAccept(accept), SupportedEncodings())
 	}
 	// no Accept specified; if Content-Type was given use it
 	contentType := GetRequestContentType(request)
@@ -0,0 +1,116 @@
+package svr
+
+import (
+	"sort"
+	"testing"
+
+	protobuf "github.com/golang/protobuf/proto"
+
+	"github.com/bodenr/vehicle-api/svr/proto"
+)
+
+// registeredMediaTypes returns the canonical media types of the registered encodings.
+func registeredMediaTypes() []string {
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+	var mediaTypes []string
+	for mediaType, reg := range registry.byType {
+		if reg.mediaType == mediaType {
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+	}
+	sort.Strings(mediaTypes)
+	return mediaTypes
+}
+
+// wrapsLists returns true if the encoding can't represent a bare array so encodes the VehicleList.
+func wrapsLists(mediaType string) bool {
+	switch mediaType {
+	case ContentAppXML, ContentAppProblemXML, ContentAppProtobuf:
+		return true
+	}
+	return false
+}
+
+func testVehicle(vin string) *proto.Vehicle {
+	return &proto.Vehicle{
+		Vin:           vin,
+		Make:          "Ford",
+		Model:         "F150",
+		Year:          2020,
+		ExteriorColor: "White",
+		InteriorColor: "Tan",
+		CreatedAt:     1600000000000,
+		CreatedBy:     "anonymous",
+		UpdatedAt:     1600000001000,
+		UpdatedBy:     "key-1",
+		Version:       2,
+	}
+}
+
+func TestEncodingRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload protobuf.Message
+		decoded func() protobuf.Message
+	}{
+		{"vehicle", testVehicle("1FTEW1EP5JFA00001"),
+			func() protobuf.Message { return &proto.Vehicle{} }},
+		{"list", &proto.VehicleList{Vehicles: []*proto.Vehicle{
+			testVehicle("1FTEW1EP5JFA00001"), testVehicle("1FTEW1EP5JFA00002")}},
+			func() protobuf.Message { return &proto.VehicleList{} }},
+		{"empty list", &proto.VehicleList{},
+			func() protobuf.Message { return &proto.VehicleList{} }},
+		{"error", &proto.ErrorResponse{Message: "Vehicle doesn't exist"},
+			func() protobuf.Message { return &proto.ErrorResponse{} }},
+		{"problem", &proto.Problem{
+			Type:      ProblemTypeDefault,
+			Title:     "Bad Request",
+			Status:    400,
+			Detail:    "Invalid vehicle",
+			Instance:  "/api/vehicles",
+			RequestId: "c0ffee",
+			Errors:    []*proto.FieldViolation{{Field: "year", Rule: "range", Message: "year is out of range"}},
+		}, func() protobuf.Message { return &proto.Problem{} }},
+	}
+	for _, mediaType := range registeredMediaTypes() {
+		for _, test := range tests {
+			t.Run(mediaType+"/"+test.name, func(t *testing.T) {
+				data, err := Marshal(mediaType, test.payload)
+				if err != nil {
+					t.Fatalf("unexpected marshal error: %v", err)
+				}
+				decoded := test.decoded()
+				if err = Unmarshal(mediaType, data, decoded); err != nil {
+					t.Fatalf("unexpected unmarshal error: %v", err)
+				}
+				if !protobuf.Equal(test.payload, decoded) {
+					t.Fatalf("expected %v, got %v", test.payload, decoded)
+				}
+			})
+		}
+	}
+}
+
+func TestEncodingListShape(t *testing.T) {
+	list := &proto.VehicleList{Vehicles: []*proto.Vehicle{testVehicle("1FTEW1EP5JFA00001")}}
+	for _, mediaType := range registeredMediaTypes() {
+		if wrapsLists(mediaType) {
+			continue
+		}
+		t.Run(mediaType, func(t *testing.T) {
+			data, err := Marshal(mediaType, list)
+			if err != nil {
+				t.Fatalf("unexpected marshal error: %v", err)
+			}
+			var vehicles []map[string]interface{}
+			if err = Unmarshal(mediaType, data, &vehicles); err != nil {
+				t.Fatalf("expected a bare array: %v", err)
+			}
+			if len(vehicles) != 1 || vehicles[0]["vin"] != "1FTEW1EP5JFA00001" ||
+				vehicles[0]["exterior_color"] != "White" {
+				t.Fatalf("unexpected vehicles: %v", vehicles)
+			}
+		})
+	}
+}
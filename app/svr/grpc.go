@@ -5,14 +5,19 @@ import (
 	"net"
 	"net/http"
 	"net/url"
-	"os"
 
 	"github.com/bodenr/vehicle-api/config"
 
 	"github.com/bodenr/vehicle-api/log"
+	"github.com/bodenr/vehicle-api/metrics"
 	"github.com/bodenr/vehicle-api/svr/proto"
+	"github.com/bodenr/vehicle-api/svr/proto/admin"
+	v2 "github.com/bodenr/vehicle-api/svr/proto/v2"
+	"github.com/bodenr/vehicle-api/tracing"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
 )
 
@@ -20,123 +25,231 @@ import (
 type GrpcHandler struct {
 	proto.UnimplementedVehicleStoreServer
 	Resource StoredResource
+
+	// Validators validate requests of all GRPC services before they're handled, in addition to the
+	// Validate method of request messages.
+	Validators []RequestValidator
+
+	// Policy authorizes requests; all requests are allowed if nil. It's set from the Auth of the
+	// server.
+	Policy *Policy
 }
 
 // GrpcServer the GRPC server and listener.
 type GrpcServer struct {
 	Server   *grpc.Server
 	Listener net.Listener
+	address  string
+	reloader *CertReloader
 }
 
 // NewGrpcServer creates a new GrpcServer for the given config and handler.
-func NewGrpcServer(conf *config.GrpcConfig, handler *GrpcHandler) (*GrpcServer, error) {
-	listener, err := net.Listen("tcp", conf.Address)
-	if err != nil {
-		return nil, err
+// A server without an address has no listener of its own and must be started using Serve.
+// Requests aren't authenticated if auth is nil; requests are scoped to the tenant resolved by tenancy.
+func NewGrpcServer(conf *config.GrpcConfig, handler *GrpcHandler, health *Health, auth *Auth,
+	tenancy *Tenancy) (*GrpcServer, error) {
+	validators := append([]RequestValidator{ValidateMessage}, handler.Validators...)
+	// NB: panics are recovered within the metrics and logging interceptors so they're recorded as
+	// Internal errors
+	unary := []grpc.UnaryServerInterceptor{
+		tracing.UnaryServerInterceptor(),
+		RequestIDUnaryInterceptor,
+		LoggingUnaryInterceptor,
+		metrics.UnaryServerInterceptor,
+		RecoveryUnaryInterceptor,
 	}
-	log.Log.Info().Str(log.Hostname, conf.Address).Msg("created grpc listener")
-
-	// TODO: TLS and all that other goodness
-	server := grpc.NewServer()
+	stream := []grpc.StreamServerInterceptor{
+		tracing.StreamServerInterceptor(),
+		RequestIDStreamInterceptor,
+		LoggingStreamInterceptor,
+		metrics.StreamServerInterceptor,
+		RecoveryStreamInterceptor,
+	}
+	if auth != nil {
+		handler.Policy = auth.policy
+		unary = append(unary, auth.UnaryServerInterceptor)
+		stream = append(stream, auth.StreamServerInterceptor)
+	}
+	unary = append(unary, tenancy.UnaryServerInterceptor)
+	stream = append(stream, tenancy.StreamServerInterceptor)
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(append(unary, ValidationUnaryInterceptor(validators...))...),
+		grpc.ChainStreamInterceptor(append(stream, ValidationStreamInterceptor(validators...))...),
+	}
+	var reloader *CertReloader
+	if conf.TLS.Enabled() {
+		tlsConf, tlsReloader, err := NewTLSConfig(&conf.TLS)
+		if err != nil {
+			return nil, err
+		}
+		reloader = tlsReloader
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConf)))
+		log.Log.Info().Str(log.CertFile, conf.TLS.CertFile).Msg("enabled grpc tls")
+	}
+	server := grpc.NewServer(opts...)
 	proto.RegisterVehicleStoreServer(server, handler)
+	v2.RegisterVehicleStoreServer(server, &GrpcHandlerV2{Resource: handler.Resource, Policy: handler.Policy})
+	if conf.Admin {
+		admin.RegisterLogAdminServer(server, &GrpcAdminHandler{})
+		if auth == nil {
+			log.Log.Warn().Msg("grpc admin services are enabled without authentication")
+		}
+	}
+	if conf.Reflection {
+		if err := registerDescriptors(); err != nil {
+			if reloader != nil {
+				reloader.Stop()
+			}
+			return nil, err
+		}
+		reflection.Register(server)
+	}
+	if health != nil {
+		health.RegisterGrpc(server)
+	}
 
 	return &GrpcServer{
 		Server:   server,
-		Listener: listener,
+		address:  conf.Address,
+		reloader: reloader,
 	}, nil
 }
 
-// Run starts the GRPC server and is blocking.
+// Name returns the component name of the GrpcServer.
+func (server *GrpcServer) Name() string {
+	return "grpc"
+}
+
+// Start creates the listener of the GRPC server.
+func (server *GrpcServer) Start(context.Context) error {
+	listener, err := net.Listen("tcp", server.address)
+	if err != nil {
+		return err
+	}
+	log.Log.Info().Str(log.Hostname, server.address).Msg("created grpc listener")
+	server.Listener = listener
+	return nil
+}
+
+// Run serves the GRPC server on the listener created by Start and is blocking.
 func (server *GrpcServer) Run() error {
-	log.Log.Info().Msg("starting grpc server")
-	return server.Server.Serve(server.Listener)
+	return server.Serve(server.Listener)
 }
 
-// WaitForShutdown waits on the terminate channel and shuts down the GRPC server upon signal.
-func (server *GrpcServer) WaitForShutdown(terminate <-chan os.Signal, done chan bool) {
-	termSig := <-terminate
+// Serve starts the GRPC server on the said listener and is blocking.
+func (server *GrpcServer) Serve(listener net.Listener) error {
+	log.Log.Info().Msg("starting grpc server")
+	return server.Server.Serve(listener)
+}
 
-	log.Log.Info().Str(log.Signal, termSig.String()).Msg("shutting down grpc server")
-	server.Server.GracefulStop()
-	log.Log.Info().Msg("grpc server gracefully stopped")
-	close(done)
+// Stop gracefully stops the GRPC server, waiting for pending RPCs to finish until the context is done
+// after which it's stopped forcefully.
+func (server *GrpcServer) Stop(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		server.Server.GracefulStop()
+		close(stopped)
+	}()
+	if server.reloader != nil {
+		defer server.reloader.Stop()
+	}
+	select {
+	case <-stopped:
+		log.Log.Info().Msg("grpc server gracefully stopped")
+		return nil
+	case <-ctx.Done():
+		server.Server.Stop()
+		return ctx.Err()
+	}
 }
 
 // GetVehicle handle getting a vehicle for GRPC.
 func (handler *GrpcHandler) GetVehicle(ctx context.Context, vin *proto.VehicleVIN) (*proto.Vehicle, error) {
+	if sErr := handler.Policy.Authorize(ctx, handler.Resource.Name(), VerbGet); sErr != nil {
+		return nil, storeErrorStatus(sErr, vin.GetVin())
+	}
 	vars := map[string]string{
 		"vin": vin.GetVin(),
 	}
-	resource, err := handler.Resource.Get(vars)
+	resource, err := handler.Resource.Get(ctx, vars)
 	if err != nil {
-		log.Log.Err(err.Error).Msg("Error getting vehicle")
-		code := codes.Internal
-		if err.StatusCode == http.StatusNotFound {
-			code = codes.NotFound
-		}
-		return nil, status.Error(code, err.Error.Error())
+		log.FromContext(ctx).Err(err.Error).Msg("Error getting vehicle")
+		return nil, storeErrorStatus(err, vin.GetVin())
 	}
 
+	handler.sendETag(ctx, resource)
 	v := resource.(proto.Vehicle)
 	return &v, nil
 }
 
 // CreateVehicle handler creating a vehicle over GRPC.
 func (handler *GrpcHandler) CreateVehicle(ctx context.Context, vehicle *proto.Vehicle) (*proto.Vehicle, error) {
-	if err := handler.Resource.Validate(vehicle, http.MethodPost); err != nil {
-		log.Log.Err(err).Msg("Invalid format")
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+	if sErr := handler.Policy.Authorize(ctx, handler.Resource.Name(), VerbCreate); sErr != nil {
+		return nil, storeErrorStatus(sErr, vehicle.Vin)
+	}
+	if err := handler.Resource.Validate(*vehicle, http.MethodPost); err != nil {
+		log.FromContext(ctx).Err(err).Msg("Invalid format")
+		return nil, invalidArgumentStatus(err)
 	}
-	storedResource, sErr := handler.Resource.Create(vehicle)
+	storedResource, sErr := handler.Resource.Create(ctx, *vehicle)
 	if sErr != nil {
-		log.Log.Err(sErr.Error).Msg("Error creating vehicle")
-		code := codes.Internal
+		log.FromContext(ctx).Err(sErr.Error).Msg("Error creating vehicle")
 		if sErr.StatusCode == http.StatusBadRequest {
-			code = codes.AlreadyExists
+			return nil, alreadyExistsStatus(sErr.Error, vehicle.Vin)
 		}
-		return nil, status.Error(code, sErr.Error.Error())
+		return nil, storeErrorStatus(sErr, vehicle.Vin)
 	}
+	handler.sendETag(ctx, storedResource)
 	v := storedResource.(proto.Vehicle)
 	return &v, nil
 }
 
 // UpdateVehicle handle updating a vehicle over GRPC.
 func (handler *GrpcHandler) UpdateVehicle(ctx context.Context, vehicle *proto.Vehicle) (*proto.Vehicle, error) {
-
-	if err := handler.Resource.Validate(vehicle, http.MethodPut); err != nil {
-		log.Log.Err(err).Msg("Invalid vehicle format")
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+	if sErr := handler.Policy.Authorize(ctx, handler.Resource.Name(), VerbUpdate); sErr != nil {
+		return nil, storeErrorStatus(sErr, vehicle.Vin)
+	}
+	if err := handler.Resource.Validate(*vehicle, http.MethodPut); err != nil {
+		log.FromContext(ctx).Err(err).Msg("Invalid vehicle format")
+		return nil, invalidArgumentStatus(err)
 	}
 	vars := map[string]string{
 		"vin": vehicle.Vin,
 	}
-	storedResource, sErr := handler.Resource.Update(vehicle, vars)
+	storedResource, sErr := handler.Resource.Update(ctx, *vehicle, vars, requestETag(ctx))
 	if sErr != nil {
-		log.Log.Err(sErr.Error).Msg("Error updating vehicle")
-		return nil, status.Error(codes.Internal, sErr.Error.Error())
+		log.FromContext(ctx).Err(sErr.Error).Msg("Error updating vehicle")
+		return nil, storeErrorStatus(sErr, vehicle.Vin)
 	}
+	handler.sendETag(ctx, storedResource)
 	v := storedResource.(proto.Vehicle)
 	return &v, nil
 }
 
 // DeleteVehicle handles deleting a vehicle over GRPC.
 func (handler *GrpcHandler) DeleteVehicle(ctx context.Context, vehicleVin *proto.VehicleVIN) (*proto.EmptyMessage, error) {
+	if sErr := handler.Policy.Authorize(ctx, handler.Resource.Name(), VerbDelete); sErr != nil {
+		return nil, storeErrorStatus(sErr, vehicleVin.Vin)
+	}
 	vars := map[string]string{
 		"vin": vehicleVin.Vin,
 	}
 
-	if err := handler.Resource.Delete(vars); err != nil {
-		log.Log.Err(err.Error).Str(log.VIN, vehicleVin.Vin).Msg("Error deleting vehicle")
-		return nil, status.Error(codes.Internal, err.Error.Error())
+	if err := handler.Resource.Delete(ctx, vars, requestETag(ctx)); err != nil {
+		log.FromContext(ctx).Err(err.Error).Str(log.VIN, vehicleVin.Vin).Msg("Error deleting vehicle")
+		return nil, storeErrorStatus(err, vehicleVin.Vin)
 	}
 	return &proto.EmptyMessage{}, nil
 }
 
 // ListVehicles handles listing vehicles over GRPC.
 func (handler *GrpcHandler) ListVehicles(e *proto.EmptyMessage, stream proto.VehicleStore_ListVehiclesServer) error {
-	resources, sErr := handler.Resource.List()
+	if sErr := handler.Policy.Authorize(stream.Context(), handler.Resource.Name(), VerbList); sErr != nil {
+		return storeErrorStatus(sErr, "")
+	}
+	resources, sErr := handler.Resource.List(stream.Context())
 	if sErr != nil {
-		return status.Error(codes.Internal, sErr.Error.Error())
+		return storeErrorStatus(sErr, "")
 	}
 	for _, resource := range resources {
 		v := resource.(proto.Vehicle)
@@ -149,17 +262,16 @@ func (handler *GrpcHandler) ListVehicles(e *proto.EmptyMessage, stream proto.Veh
 
 // SearchVehicles handles searching for vehciles over GRPC.
 func (handler *GrpcHandler) SearchVehicles(query *proto.VehicleQuery, stream proto.VehicleStore_SearchVehiclesServer) error {
+	if sErr := handler.Policy.Authorize(stream.Context(), handler.Resource.Name(), VerbList); sErr != nil {
+		return storeErrorStatus(sErr, "")
+	}
 	queryValues, err := url.ParseQuery(query.Query)
 	if err != nil {
 		return status.Error(codes.InvalidArgument, err.Error())
 	}
-	resources, sErr := handler.Resource.Search(queryValues)
+	resources, sErr := handler.Resource.Search(stream.Context(), queryValues)
 	if sErr != nil {
-		code := codes.Internal
-		if sErr.StatusCode == http.StatusBadRequest {
-			code = codes.InvalidArgument
-		}
-		return status.Error(code, sErr.Error.Error())
+		return storeErrorStatus(sErr, "")
 	}
 	for _, resource := range resources {
 		v := resource.(proto.Vehicle)
@@ -0,0 +1,165 @@
+package svr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/bodenr/vehicle-api/log"
+	"github.com/bodenr/vehicle-api/validation"
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// GrpcETagKey is the gRPC header metadata key holding the ETag of the returned resource.
+	GrpcETagKey = "etag"
+
+	// GrpcIfNoneMatchKey is the gRPC request metadata key holding the expected ETag of the resource
+	// being updated or deleted, mirroring the REST If-None-Match header.
+	GrpcIfNoneMatchKey = "if-none-match"
+
+	// PreconditionTypeETag is the PreconditionFailure violation type for ETag mismatches.
+	PreconditionTypeETag = "ETAG"
+
+	// vehicleResourceType is the ResourceInfo resource type of vehicles.
+	vehicleResourceType = "vehicle.Vehicle"
+)
+
+// GrpcRetryDelay is the retry delay suggested to clients in RetryInfo for transient errors.
+var GrpcRetryDelay = time.Second
+
+// grpcCode maps the http status code hint of a StoreError to a gRPC code.
+func grpcCode(statusCode int) codes.Code {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	}
+	return codes.Internal
+}
+
+// withDetails returns the status as an error with the said details attached.
+func withDetails(st *status.Status, details ...protobuf.Message) error {
+	if len(details) == 0 {
+		return st.Err()
+	}
+	detailed, err := st.WithDetails(details...)
+	if err != nil {
+		log.Log.Err(err).Msg("Failed to attach grpc error details")
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// badRequest returns BadRequest field violations for the error or nil if it has none.
+func badRequest(err error) *errdetails.BadRequest {
+	var violations validation.Errors
+	if !errors.As(err, &violations) {
+		return nil
+	}
+	details := &errdetails.BadRequest{}
+	for _, violation := range violations {
+		details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       violation.Field,
+			Description: violation.Message,
+		})
+	}
+	return details
+}
+
+// invalidArgumentStatus converts a validation error into an InvalidArgument status error.
+func invalidArgumentStatus(err error) error {
+	st := status.New(codes.InvalidArgument, err.Error())
+	if details := badRequest(err); details != nil {
+		return withDetails(st, details)
+	}
+	return st.Err()
+}
+
+// storeErrorStatus converts a StoreError for the said resource name into a gRPC status error with
+// details describing the failure.
+func storeErrorStatus(sErr *StoreError, resourceName string) error {
+	st := status.New(grpcCode(sErr.StatusCode), sErr.Error.Error())
+	switch sErr.StatusCode {
+	case http.StatusBadRequest:
+		if details := badRequest(sErr.Error); details != nil {
+			return withDetails(st, details)
+		}
+	case http.StatusNotFound:
+		return withDetails(st, &errdetails.ResourceInfo{
+			ResourceType: vehicleResourceType,
+			ResourceName: resourceName,
+			Description:  sErr.Error.Error(),
+		})
+	case http.StatusPreconditionFailed:
+		return preconditionStatus(resourceName)
+	case http.StatusServiceUnavailable:
+		return withDetails(st, &errdetails.RetryInfo{
+			RetryDelay: ptypes.DurationProto(GrpcRetryDelay),
+		})
+	}
+	return st.Err()
+}
+
+// alreadyExistsStatus returns an AlreadyExists status error for the said resource name.
+func alreadyExistsStatus(err error, resourceName string) error {
+	return withDetails(status.New(codes.AlreadyExists, err.Error()), &errdetails.ResourceInfo{
+		ResourceType: vehicleResourceType,
+		ResourceName: resourceName,
+		Description:  err.Error(),
+	})
+}
+
+// preconditionStatus returns a FailedPrecondition status error for an ETag mismatch.
+func preconditionStatus(resourceName string) error {
+	return withDetails(status.New(codes.FailedPrecondition, ErrETagMismatch.Error()),
+		&errdetails.PreconditionFailure{
+			Violations: []*errdetails.PreconditionFailure_Violation{{
+				Type:        PreconditionTypeETag,
+				Subject:     resourceName,
+				Description: ErrETagMismatch.Error(),
+			}},
+		})
+}
+
+// requestETag returns the expected ETag of the if-none-match request metadata or an empty string.
+func requestETag(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(GrpcIfNoneMatchKey); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// sendETag sends the ETag of the resource as response header metadata.
+func (handler *GrpcHandler) sendETag(ctx context.Context, resource interface{}) {
+	etag, err := handler.Resource.BuildETag(resource)
+	if err != nil {
+		log.FromContext(ctx).Err(err).Msg("Failed to build etag")
+		return
+	}
+	if err = grpc.SetHeader(ctx, metadata.Pairs(GrpcETagKey, etag)); err != nil {
+		log.FromContext(ctx).Err(err).Msg("Failed to send etag header")
+	}
+}
@@ -0,0 +1,220 @@
+package svr
+
+import (
+	"context"
+	"net"
+	"runtime/debug"
+	"time"
+
+	"github.com/bodenr/vehicle-api/log"
+	"github.com/bodenr/vehicle-api/tracing"
+	"github.com/rs/xid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// GrpcRequestIDKey is the request and response metadata key of the request ID.
+const GrpcRequestIDKey = "x-request-id"
+
+// maxRequestIDLength is the longest request ID accepted from clients, longer IDs are replaced.
+const maxRequestIDLength = 128
+
+// requestIDKey is the context key of the GRPC request ID.
+type requestIDKey struct{}
+
+// RequestValidator validates a GRPC request before it's handled; an error rejects the request and
+// is returned to the client as is if it's a status, otherwise as InvalidArgument.
+type RequestValidator func(ctx context.Context, fullMethod string, request interface{}) error
+
+// contextStream is a ServerStream with its context replaced.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the replaced context of the stream.
+func (stream *contextStream) Context() context.Context {
+	return stream.ctx
+}
+
+// GrpcRequestID returns the request ID of the GRPC request context or an empty string.
+func GrpcRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// withRequestID returns the context with the request ID of the x-request-id metadata, or a new ID
+// if not set, along with a request logger carrying the same correlation fields as REST requests.
+// The ID is sent back as response header metadata.
+func withRequestID(ctx context.Context) context.Context {
+	var id, userAgent string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(GrpcRequestIDKey); len(values) > 0 && len(values[0]) <= maxRequestIDLength {
+			id = values[0]
+		}
+		if values := md.Get("user-agent"); len(values) > 0 {
+			userAgent = values[0]
+		}
+	}
+	if id == "" {
+		id = xid.New().String()
+	}
+	if err := grpc.SetHeader(ctx, metadata.Pairs(GrpcRequestIDKey, id)); err != nil {
+		log.FromContext(ctx).Debug().Err(err).Msg("Failed to set request ID header")
+	}
+
+	logContext := tracing.Logger(ctx).With()
+	if p, ok := peer.FromContext(ctx); ok {
+		host, _, err := net.SplitHostPort(p.Addr.String())
+		if err != nil {
+			host = p.Addr.String()
+		}
+		logContext = logContext.Str(log.IP, host)
+	}
+	if userAgent != "" {
+		logContext = logContext.Str(log.UserAgent, userAgent)
+	}
+	ctx = context.WithValue(ctx, requestIDKey{}, id)
+	return log.WithContext(ctx, logContext.Str(log.RequestID, id).Logger())
+}
+
+// RequestIDUnaryInterceptor propagates the request ID of unary requests.
+func RequestIDUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(withRequestID(ctx), req)
+}
+
+// RequestIDStreamInterceptor propagates the request ID of stream requests.
+func RequestIDStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	return handler(srv, &contextStream{ServerStream: stream, ctx: withRequestID(stream.Context())})
+}
+
+// logAccess writes the access log entry of a GRPC request, sampled as per the log config.
+func logAccess(ctx context.Context, method string, start time.Time, err error) {
+	address := "unknown"
+	if p, ok := peer.FromContext(ctx); ok {
+		address = p.Addr.String()
+	}
+	log.Sampled(log.FromContext(ctx)).Info().
+		Str(log.Method, method).
+		Str(log.Code, status.Code(err).String()).
+		Dur(log.Duration, time.Since(start)).
+		Str(log.Peer, address).
+		Msg("")
+}
+
+// LoggingUnaryInterceptor writes an access log entry for unary requests.
+func LoggingUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	logAccess(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+// LoggingStreamInterceptor writes an access log entry for stream requests once the stream ends.
+func LoggingStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, stream)
+	logAccess(stream.Context(), info.FullMethod, start, err)
+	return err
+}
+
+// recoverStatus converts a recovered panic into an Internal status error, logging it with its stack.
+func recoverStatus(ctx context.Context, method string, recovered interface{}) error {
+	log.FromContext(ctx).Error().
+		Str(log.Method, method).
+		Interface(log.Panic, recovered).
+		Bytes(log.Stack, debug.Stack()).
+		Msg("Recovered from panic handling grpc request")
+	return status.Error(codes.Internal, "Internal error")
+}
+
+// RecoveryUnaryInterceptor returns an Internal status instead of crashing when unary handlers panic.
+func RecoveryUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = recoverStatus(ctx, info.FullMethod, recovered)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// RecoveryStreamInterceptor returns an Internal status instead of crashing when stream handlers panic.
+func RecoveryStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = recoverStatus(stream.Context(), info.FullMethod, recovered)
+		}
+	}()
+	return handler(srv, stream)
+}
+
+// validate runs the validators on the request.
+func validate(ctx context.Context, validators []RequestValidator, fullMethod string, request interface{}) error {
+	for _, validator := range validators {
+		if err := validator(ctx, fullMethod, request); err != nil {
+			if _, ok := status.FromError(err); ok {
+				return err
+			}
+			return invalidArgumentStatus(err)
+		}
+	}
+	return nil
+}
+
+// ValidationUnaryInterceptor validates unary requests with the said validators before handling them.
+func ValidationUnaryInterceptor(validators ...RequestValidator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		if err := validate(ctx, validators, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// validatingStream validates each message received on a stream.
+type validatingStream struct {
+	grpc.ServerStream
+	method     string
+	validators []RequestValidator
+}
+
+// RecvMsg receives and validates the next message of the stream.
+func (stream *validatingStream) RecvMsg(m interface{}) error {
+	if err := stream.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return validate(stream.Context(), stream.validators, stream.method, m)
+}
+
+// ValidationStreamInterceptor validates the messages received on streams with the said validators.
+func ValidationStreamInterceptor(validators ...RequestValidator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		if len(validators) == 0 {
+			return handler(srv, stream)
+		}
+		return handler(srv, &validatingStream{
+			ServerStream: stream,
+			method:       info.FullMethod,
+			validators:   validators,
+		})
+	}
+}
+
+// ValidateMessage is a RequestValidator for messages with a Validate method.
+func ValidateMessage(ctx context.Context, fullMethod string, request interface{}) error {
+	if validator, ok := request.(interface{ Validate() error }); ok {
+		return validator.Validate()
+	}
+	return nil
+}
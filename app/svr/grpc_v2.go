@@ -0,0 +1,163 @@
+package svr
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/bodenr/vehicle-api/log"
+	"github.com/bodenr/vehicle-api/svr/proto"
+	v2 "github.com/bodenr/vehicle-api/svr/proto/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GrpcHandlerV2 wraps vehicle.v2 GRPC handling for the given StoredResource.
+type GrpcHandlerV2 struct {
+	v2.UnimplementedVehicleStoreServer
+	Resource StoredResource
+
+	// Policy authorizes requests; all requests are allowed if nil.
+	Policy *Policy
+}
+
+// toVehicleResource converts a stored vehicle into a VehicleResource.
+func (handler *GrpcHandlerV2) toVehicleResource(ctx context.Context, resource interface{}) *v2.VehicleResource {
+	vehicle := resource.(proto.Vehicle)
+	etag, err := handler.Resource.BuildETag(resource)
+	if err != nil {
+		log.FromContext(ctx).Err(err).Msg("Failed to build etag")
+	}
+	return &v2.VehicleResource{
+		Vin:           vehicle.Vin,
+		Make:          vehicle.Make,
+		Model:         vehicle.Model,
+		Year:          vehicle.Year,
+		ExteriorColor: vehicle.ExteriorColor,
+		InteriorColor: vehicle.InteriorColor,
+		CreatedAt:     vehicle.CreatedAt,
+		CreatedBy:     vehicle.CreatedBy,
+		UpdatedAt:     vehicle.UpdatedAt,
+		UpdatedBy:     vehicle.UpdatedBy,
+		Etag:          etag,
+		Version:       vehicle.Version,
+	}
+}
+
+// sendAll sends the said stored vehicles on the stream.
+func (handler *GrpcHandlerV2) sendAll(ctx context.Context, resources []interface{},
+	send func(*v2.VehicleResource) error) error {
+
+	for _, resource := range resources {
+		if err := send(handler.toVehicleResource(ctx, resource)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetVehicle handles getting a vehicle over GRPC.
+func (handler *GrpcHandlerV2) GetVehicle(ctx context.Context, request *v2.GetVehicleRequest) (*v2.VehicleResource, error) {
+	if sErr := handler.Policy.Authorize(ctx, handler.Resource.Name(), VerbGet); sErr != nil {
+		return nil, storeErrorStatus(sErr, request.Vin)
+	}
+	resource, sErr := handler.Resource.Get(ctx, RequestVars{"vin": request.Vin})
+	if sErr != nil {
+		log.FromContext(ctx).Err(sErr.Error).Msg("Error getting vehicle")
+		return nil, storeErrorStatus(sErr, request.Vin)
+	}
+	return handler.toVehicleResource(ctx, resource), nil
+}
+
+// CreateVehicle handles creating a vehicle over GRPC.
+func (handler *GrpcHandlerV2) CreateVehicle(ctx context.Context, request *v2.CreateVehicleRequest) (*v2.VehicleResource, error) {
+	if sErr := handler.Policy.Authorize(ctx, handler.Resource.Name(), VerbCreate); sErr != nil {
+		return nil, storeErrorStatus(sErr, request.Vin)
+	}
+	vehicle := proto.Vehicle{
+		Vin:           request.Vin,
+		Make:          request.Make,
+		Model:         request.Model,
+		Year:          request.Year,
+		ExteriorColor: request.ExteriorColor,
+		InteriorColor: request.InteriorColor,
+	}
+	if err := handler.Resource.Validate(vehicle, http.MethodPost); err != nil {
+		log.FromContext(ctx).Err(err).Msg("Invalid format")
+		return nil, invalidArgumentStatus(err)
+	}
+	resource, sErr := handler.Resource.Create(ctx, vehicle)
+	if sErr != nil {
+		log.FromContext(ctx).Err(sErr.Error).Msg("Error creating vehicle")
+		if sErr.StatusCode == http.StatusBadRequest {
+			return nil, alreadyExistsStatus(sErr.Error, request.Vin)
+		}
+		return nil, storeErrorStatus(sErr, request.Vin)
+	}
+	return handler.toVehicleResource(ctx, resource), nil
+}
+
+// UpdateVehicle handles updating a vehicle over GRPC.
+func (handler *GrpcHandlerV2) UpdateVehicle(ctx context.Context, request *v2.UpdateVehicleRequest) (*v2.VehicleResource, error) {
+	if sErr := handler.Policy.Authorize(ctx, handler.Resource.Name(), VerbUpdate); sErr != nil {
+		return nil, storeErrorStatus(sErr, request.Vin)
+	}
+	vehicle := proto.Vehicle{
+		Vin:           request.Vin,
+		Make:          request.Make,
+		Model:         request.Model,
+		Year:          request.Year,
+		ExteriorColor: request.ExteriorColor,
+		InteriorColor: request.InteriorColor,
+	}
+	if err := handler.Resource.Validate(vehicle, http.MethodPut); err != nil {
+		log.FromContext(ctx).Err(err).Msg("Invalid vehicle format")
+		return nil, invalidArgumentStatus(err)
+	}
+	resource, sErr := handler.Resource.Update(ctx, vehicle, RequestVars{"vin": request.Vin}, request.Etag)
+	if sErr != nil {
+		log.FromContext(ctx).Err(sErr.Error).Msg("Error updating vehicle")
+		return nil, storeErrorStatus(sErr, request.Vin)
+	}
+	return handler.toVehicleResource(ctx, resource), nil
+}
+
+// DeleteVehicle handles deleting a vehicle over GRPC.
+func (handler *GrpcHandlerV2) DeleteVehicle(ctx context.Context, request *v2.DeleteVehicleRequest) (*v2.DeleteVehicleResponse, error) {
+	if sErr := handler.Policy.Authorize(ctx, handler.Resource.Name(), VerbDelete); sErr != nil {
+		return nil, storeErrorStatus(sErr, request.Vin)
+	}
+	if sErr := handler.Resource.Delete(ctx, RequestVars{"vin": request.Vin}, request.Etag); sErr != nil {
+		log.FromContext(ctx).Err(sErr.Error).Str(log.VIN, request.Vin).Msg("Error deleting vehicle")
+		return nil, storeErrorStatus(sErr, request.Vin)
+	}
+	return &v2.DeleteVehicleResponse{}, nil
+}
+
+// ListVehicles handles listing vehicles over GRPC.
+func (handler *GrpcHandlerV2) ListVehicles(request *v2.ListVehiclesRequest, stream v2.VehicleStore_ListVehiclesServer) error {
+	if sErr := handler.Policy.Authorize(stream.Context(), handler.Resource.Name(), VerbList); sErr != nil {
+		return storeErrorStatus(sErr, "")
+	}
+	resources, sErr := handler.Resource.List(stream.Context())
+	if sErr != nil {
+		return storeErrorStatus(sErr, "")
+	}
+	return handler.sendAll(stream.Context(), resources, stream.Send)
+}
+
+// SearchVehicles handles searching for vehicles over GRPC.
+func (handler *GrpcHandlerV2) SearchVehicles(request *v2.SearchVehiclesRequest, stream v2.VehicleStore_SearchVehiclesServer) error {
+	if sErr := handler.Policy.Authorize(stream.Context(), handler.Resource.Name(), VerbList); sErr != nil {
+		return storeErrorStatus(sErr, "")
+	}
+	queryValues, err := url.ParseQuery(request.Query)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	resources, sErr := handler.Resource.Search(stream.Context(), queryValues)
+	if sErr != nil {
+		return storeErrorStatus(sErr, "")
+	}
+	return handler.sendAll(stream.Context(), resources, stream.Send)
+}
@@ -0,0 +1,242 @@
+package svr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bodenr/vehicle-api/config"
+	"github.com/bodenr/vehicle-api/log"
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	// HealthStatusOK is the status of passing health checks.
+	HealthStatusOK = "ok"
+
+	// HealthStatusFailed is the status of failing health checks.
+	HealthStatusFailed = "failed"
+
+	// healthCheckTimeout is the deadline for running all readiness checks.
+	healthCheckTimeout = 2 * time.Second
+)
+
+// ErrShuttingDown is the readiness error reported once the application has begun shutting down.
+var ErrShuttingDown = errors.New("Shutting down")
+
+// ReadinessCheck returns an error if a dependency isn't ready to serve requests.
+type ReadinessCheck func(ctx context.Context) error
+
+// namedCheck is a ReadinessCheck along with its name.
+type namedCheck struct {
+	name  string
+	check ReadinessCheck
+}
+
+// HealthResponse is the body of the liveness and readiness endpoints.
+type HealthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Health reports the liveness and readiness of the application over REST and the GRPC health
+// service. It's a lifecycle component that's registered after the servers so that it's stopped
+// first, reporting not ready while the servers drain.
+type Health struct {
+	conf         *config.HealthConfig
+	lock         sync.RWMutex
+	checks       []namedCheck
+	grpcServers  []*health.Server
+	grpcServices []string
+	shuttingDown bool
+	stop         chan struct{}
+}
+
+// NewHealth creates a new Health for the said config.
+func NewHealth(conf *config.HealthConfig) *Health {
+	return &Health{
+		conf: conf,
+		stop: make(chan struct{}),
+	}
+}
+
+// AddCheck adds a named readiness check.
+func (h *Health) AddCheck(name string, check ReadinessCheck) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// BindRoutes binds the liveness and readiness endpoints to the router.
+func (h *Health) BindRoutes(router *mux.Router) {
+	router.HandleFunc("/healthz", h.Live).Methods(http.MethodGet)
+	router.HandleFunc("/readyz", h.Ready).Methods(http.MethodGet)
+}
+
+// RegisterGrpc registers the GRPC health service on the server, reporting the status of each of its
+// services as well as the overall status under the empty service name.
+func (h *Health) RegisterGrpc(server *grpc.Server) {
+	healthServer := health.NewServer()
+	// NB: the server is only reported as serving once the readiness checks pass
+	healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for service := range server.GetServiceInfo() {
+		healthServer.SetServingStatus(service, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+		h.grpcServices = append(h.grpcServices, service)
+	}
+	grpc_health_v1.RegisterHealthServer(server, healthServer)
+	h.grpcServers = append(h.grpcServers, healthServer)
+}
+
+// check runs the readiness checks updating the GRPC health status; the results are keyed by check name.
+func (h *Health) check(ctx context.Context) (map[string]error, bool) {
+	h.lock.RLock()
+	checks := h.checks
+	shuttingDown := h.shuttingDown
+	h.lock.RUnlock()
+
+	results := map[string]error{}
+	ready := !shuttingDown
+	if shuttingDown {
+		results["shutdown"] = ErrShuttingDown
+	}
+	for _, check := range checks {
+		if err := check.check(ctx); err != nil {
+			results[check.name] = err
+			ready = false
+		} else {
+			results[check.name] = nil
+		}
+	}
+	h.setGrpcStatus(ready)
+	return results, ready
+}
+
+// setGrpcStatus sets the status of all GRPC services; once shutting down they're never reported as
+// serving again.
+func (h *Health) setGrpcStatus(ready bool) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	status := grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	if ready && !h.shuttingDown {
+		status = grpc_health_v1.HealthCheckResponse_SERVING
+	}
+	for _, healthServer := range h.grpcServers {
+		healthServer.SetServingStatus("", status)
+		for _, service := range h.grpcServices {
+			healthServer.SetServingStatus(service, status)
+		}
+	}
+}
+
+// Live handles the liveness endpoint which succeeds as long as the process can serve requests.
+func (h *Health) Live(writer http.ResponseWriter, request *http.Request) {
+	respondHealth(writer, http.StatusOK, &HealthResponse{Status: HealthStatusOK})
+}
+
+// Ready handles the readiness endpoint which fails if any readiness check fails or the application
+// is shutting down.
+func (h *Health) Ready(writer http.ResponseWriter, request *http.Request) {
+	ctx, cancel := context.WithTimeout(request.Context(), healthCheckTimeout)
+	defer cancel()
+
+	results, ready := h.check(ctx)
+	response := &HealthResponse{
+		Status: HealthStatusOK,
+		Checks: map[string]string{},
+	}
+	for name, err := range results {
+		if err != nil {
+			response.Checks[name] = err.Error()
+		} else {
+			response.Checks[name] = HealthStatusOK
+		}
+	}
+	code := http.StatusOK
+	if !ready {
+		response.Status = HealthStatusFailed
+		code = http.StatusServiceUnavailable
+	}
+	respondHealth(writer, code, response)
+}
+
+// respondHealth writes the health response as json.
+func respondHealth(writer http.ResponseWriter, code int, response *HealthResponse) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		log.Log.Err(err).Msg("Failed to marshal health response")
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", ContentAppJSON)
+	writer.Header().Set("Cache-Control", "no-store")
+	writer.WriteHeader(code)
+	writer.Write(data)
+}
+
+// Name returns the component name of the Health.
+func (h *Health) Name() string {
+	return "health"
+}
+
+// Start runs the readiness checks and keeps the GRPC health status up to date until stopped.
+func (h *Health) Start(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	if _, ready := h.check(ctx); !ready {
+		log.Log.Warn().Msg("readiness checks failing")
+	}
+	if h.conf.CheckInterval > 0 {
+		go h.watch()
+	}
+	return nil
+}
+
+// watch periodically runs the readiness checks until stopped.
+func (h *Health) watch() {
+	ticker := time.NewTicker(h.conf.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+			h.check(ctx)
+			cancel()
+		}
+	}
+}
+
+// Stop reports the application as not ready and waits for the drain delay, or the context to be
+// done, before returning so the servers keep serving while load balancers catch up.
+func (h *Health) Stop(ctx context.Context) error {
+	h.lock.Lock()
+	if h.shuttingDown {
+		h.lock.Unlock()
+		return nil
+	}
+	h.shuttingDown = true
+	close(h.stop)
+	h.lock.Unlock()
+
+	h.setGrpcStatus(false)
+	log.Log.Info().Dur(log.DrainDelay, h.conf.DrainDelay).Msg("reporting not ready")
+
+	timer := time.NewTimer(h.conf.DrainDelay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
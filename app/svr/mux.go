@@ -0,0 +1,143 @@
+package svr
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"strings"
+
+	"github.com/bodenr/vehicle-api/config"
+	"github.com/bodenr/vehicle-api/log"
+	"github.com/soheilhy/cmux"
+)
+
+// MuxServer serves a RestServer and GrpcServer on a single port, routing HTTP/2 connections with a
+// gRPC content type to the GrpcServer and everything else to the RestServer. The RestServer is
+// served over HTTP/1.1 only.
+type MuxServer struct {
+	Rest      *RestServer
+	Grpc      *GrpcServer
+	Listener  net.Listener
+	address   string
+	tlsConfig *tls.Config
+	reloader  *CertReloader
+}
+
+// NewMuxServer creates a new MuxServer for the said configs; the address and TLS settings of the
+// HTTP and GRPC configs are replaced by those of the MuxConfig.
+func NewMuxServer(conf *config.MuxConfig, httpConf config.HTTPConfig, grpcConf config.GrpcConfig,
+	handler *GrpcHandler, health *Health, auth *Auth, tenancy *Tenancy,
+	storedResources ...StoredResource) (*MuxServer, error) {
+
+	// NB: TLS is terminated by the mux listener so both servers are served in plaintext
+	httpConf.Address, httpConf.RedirectAddress, httpConf.TLS = conf.Address, "", config.TLSConfig{}
+	grpcConf.Address, grpcConf.TLS = "", config.TLSConfig{}
+
+	rest, err := NewRestServer(&httpConf, health, auth, tenancy, storedResources...)
+	if err != nil {
+		return nil, err
+	}
+	grpcServer, err := NewGrpcServer(&grpcConf, handler, health, auth, tenancy)
+	if err != nil {
+		// NB: the servers aren't started so stopping them only releases resources, e.g. reloaders
+		rest.Stop(context.Background())
+		return nil, err
+	}
+
+	server := &MuxServer{
+		Rest:    rest,
+		Grpc:    grpcServer,
+		address: conf.Address,
+	}
+	if conf.TLS.Enabled() {
+		if server.tlsConfig, server.reloader, err = NewTLSConfig(&conf.TLS); err != nil {
+			server.Stop(context.Background())
+			return nil, err
+		}
+		server.tlsConfig.NextProtos = []string{"h2"}
+		server.tlsConfig.GetConfigForClient = restTLSConfig(server.tlsConfig)
+		log.Log.Info().Str(log.CertFile, conf.TLS.CertFile).Msg("enabled mux tls")
+	}
+	return server, nil
+}
+
+// restTLSConfig returns a tls.Config GetConfigForClient callback negotiating HTTP/1.1 with clients
+// that support it, i.e. only offering h2 to gRPC clients, which don't. REST is served by net/http
+// on plaintext mux connections, which can't speak HTTP/2.
+func restTLSConfig(tlsConfig *tls.Config) func(*tls.ClientHelloInfo) (*tls.Config, error) {
+	restConfig := tlsConfig.Clone()
+	restConfig.NextProtos = []string{"http/1.1"}
+	return func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		for _, proto := range hello.SupportedProtos {
+			if proto == "http/1.1" {
+				return restConfig, nil
+			}
+		}
+		return nil, nil
+	}
+}
+
+// Name returns the component name of the MuxServer.
+func (server *MuxServer) Name() string {
+	return "mux"
+}
+
+// Start creates the shared listener, terminating TLS if enabled.
+func (server *MuxServer) Start(context.Context) error {
+	listener, err := net.Listen("tcp", server.address)
+	if err != nil {
+		return err
+	}
+	log.Log.Info().Str(log.Hostname, server.address).Msg("created mux listener")
+	if server.tlsConfig != nil {
+		listener = tls.NewListener(listener, server.tlsConfig)
+	}
+	server.Listener = listener
+	return nil
+}
+
+// Run starts the RestServer and GrpcServer on the shared listener and is blocking; it returns when
+// the listener is closed or either server fails.
+func (server *MuxServer) Run() error {
+	mux := cmux.New(server.Listener)
+	// NB: gRPC clients wait for the server's HTTP/2 SETTINGS frame before sending headers
+	grpcListener := mux.MatchWithWriters(
+		cmux.HTTP2MatchHeaderFieldPrefixSendSettings("content-type", "application/grpc"))
+	restListener := mux.Match(cmux.Any())
+
+	errs := make(chan error, 3)
+	go func() {
+		errs <- server.Grpc.Serve(grpcListener)
+	}()
+	go func() {
+		errs <- server.Rest.Serve(restListener)
+	}()
+	go func() {
+		errs <- mux.Serve()
+	}()
+
+	err := <-errs
+	if err != nil && isClosedError(err) {
+		return nil
+	}
+	return err
+}
+
+// isClosedError returns true if the error is the result of the shared listener being closed.
+func isClosedError(err error) bool {
+	return err == cmux.ErrListenerClosed || strings.Contains(err.Error(), "use of closed network connection")
+}
+
+// Stop gracefully shuts down the RestServer and GrpcServer, waiting for active connections until the
+// context is done.
+func (server *MuxServer) Stop(ctx context.Context) error {
+	if server.reloader != nil {
+		defer server.reloader.Stop()
+	}
+	// NB: the REST shutdown closes the shared listener which stops the mux
+	restErr := server.Rest.Stop(ctx)
+	if err := server.Grpc.Stop(ctx); err != nil {
+		return err
+	}
+	return restErr
+}
@@ -0,0 +1,197 @@
+package svr
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MediaRange is a single media range parsed from an Accept header as per RFC 7231 section 5.3.2.
+type MediaRange struct {
+	// Type is the top level type or "*".
+	Type string
+
+	// Subtype is the subtype or "*".
+	Subtype string
+
+	// Params are the media type parameters excluding the quality value and accept extensions.
+	Params map[string]string
+
+	// Quality is the q-value weight of the range between 0 and 1.
+	Quality float64
+
+	// index is the position of the range in the request used to break ties.
+	index int
+}
+
+// ParseMediaType parses the said media type returning the lower cased "type/subtype" and its parameters.
+func ParseMediaType(value string) (string, map[string]string, error) {
+	mediaType, params, err := mime.ParseMediaType(value)
+	if err != nil {
+		return "", nil, err
+	}
+	return strings.ToLower(mediaType), params, nil
+}
+
+// ParseAccept parses the said Accept header values into media ranges ordered by preference.
+// Ranges that are malformed are ignored.
+func ParseAccept(values []string) []MediaRange {
+	var ranges []MediaRange
+	for _, value := range values {
+		for _, element := range splitQuoted(value, ',') {
+			element = strings.TrimSpace(element)
+			if element == "" {
+				continue
+			}
+			mediaRange, ok := parseMediaRange(element)
+			if !ok {
+				continue
+			}
+			mediaRange.index = len(ranges)
+			ranges = append(ranges, mediaRange)
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].Quality != ranges[j].Quality {
+			return ranges[i].Quality > ranges[j].Quality
+		}
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+	return ranges
+}
+
+// parseMediaRange parses a single Accept header element.
+func parseMediaRange(element string) (MediaRange, bool) {
+	mediaType, params, err := ParseMediaType(element)
+	if err != nil {
+		return MediaRange{}, false
+	}
+	slash := strings.Index(mediaType, "/")
+	if slash <= 0 || slash == len(mediaType)-1 {
+		return MediaRange{}, false
+	}
+	mediaRange := MediaRange{
+		Type:    mediaType[:slash],
+		Subtype: mediaType[slash+1:],
+		Params:  map[string]string{},
+		Quality: 1,
+	}
+	if mediaRange.Type == "*" && mediaRange.Subtype != "*" {
+		return MediaRange{}, false
+	}
+	// NB: mime.ParseMediaType doesn't preserve order so any parameter named q is treated as the
+	// weight and parameters that follow it as accept extensions are not distinguished
+	for key, value := range params {
+		if key == "q" {
+			quality, err := strconv.ParseFloat(value, 64)
+			if err != nil || quality < 0 || quality > 1 {
+				return MediaRange{}, false
+			}
+			mediaRange.Quality = quality
+			continue
+		}
+		mediaRange.Params[key] = value
+	}
+	return mediaRange, true
+}
+
+// specificity ranks how specific the media range is; higher is more specific.
+func (mediaRange MediaRange) specificity() int {
+	switch {
+	case mediaRange.Type == "*":
+		return 0
+	case mediaRange.Subtype == "*":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Matches returns if the media range includes the said "type/subtype" media type.
+func (mediaRange MediaRange) Matches(mediaType string) bool {
+	slash := strings.Index(mediaType, "/")
+	if slash < 0 {
+		return false
+	}
+	if mediaRange.Type != "*" && mediaRange.Type != mediaType[:slash] {
+		return false
+	}
+	return mediaRange.Subtype == "*" || mediaRange.Subtype == mediaType[slash+1:]
+}
+
+// Negotiate returns the best of the said offered media types for the parsed accept ranges, or an
+// empty string when none are acceptable. The quality of an offer is taken from the most specific
+// range that matches it; ties are broken by the order of the ranges in the request and then by the
+// order of the offers.
+func Negotiate(ranges []MediaRange, offers []string) string {
+	best := ""
+	bestQuality := 0.0
+	bestIndex := 0
+	for _, offer := range offers {
+		matched := false
+		var match MediaRange
+		for _, mediaRange := range ranges {
+			if !mediaRange.Matches(offer) {
+				continue
+			}
+			if !matched || mediaRange.specificity() > match.specificity() {
+				match = mediaRange
+				matched = true
+			}
+		}
+		if !matched || match.Quality <= 0 {
+			continue
+		}
+		if best == "" || match.Quality > bestQuality ||
+			(match.Quality == bestQuality && match.index < bestIndex) {
+			best = offer
+			bestQuality = match.Quality
+			bestIndex = match.index
+		}
+	}
+	return best
+}
+
+// splitQuoted splits the said value on the separator ignoring separators within quoted strings.
+func splitQuoted(value string, separator rune) []string {
+	var parts []string
+	quoted := false
+	escaped := false
+	start := 0
+	for i, char := range value {
+		switch {
+		case escaped:
+			escaped = false
+		case char == '\\' && quoted:
+			escaped = true
+		case char == '"':
+			quoted = !quoted
+		case char == separator && !quoted:
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, value[start:])
+}
+
+// ErrNotAcceptable is the error used when no supported encoding satisfies the request Accept header.
+var ErrNotAcceptable = errors.New("No supported content type is acceptable")
+
+// NegotiationHandler is middleware that rejects requests whose Accept header can't be satisfied by
+// any supported encoding with 406 Not Acceptable, responding with an error in the said format. It
+// also marks responses as varying on Accept.
+func NegotiationHandler(errorFormat string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.Header().Add("Vary", "Accept")
+			if GetResponseContentType(request) == "" {
+				respondErr(writer, request, errorFormat, http.StatusNotAcceptable, ErrNotAcceptable)
+				return
+			}
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package svr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/bodenr/vehicle-api/log"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// VerbGet is the permission to get a single resource.
+	VerbGet = "get"
+
+	// VerbList is the permission to list and search resources.
+	VerbList = "list"
+
+	// VerbCreate is the permission to create resources.
+	VerbCreate = "create"
+
+	// VerbUpdate is the permission to update resources.
+	VerbUpdate = "update"
+
+	// VerbDelete is the permission to delete resources.
+	VerbDelete = "delete"
+
+	// PolicyWildcard grants all verbs, when used as verb, or all resources, when used as resource.
+	PolicyWildcard = "*"
+)
+
+// policyVerbs are the verbs that can be granted by a policy.
+var policyVerbs = []string{VerbGet, VerbList, VerbCreate, VerbUpdate, VerbDelete, PolicyWildcard}
+
+var (
+	// ErrPermissionDenied is the error used when the principal of a request lacks the permission.
+	ErrPermissionDenied = errors.New("Permission denied")
+
+	// ErrRolesWithoutPolicy is the error used when creating API keys with roles while requests
+	// aren't authorized.
+	ErrRolesWithoutPolicy = errors.New("API key roles require an authorization policy")
+)
+
+// policyFile is the yaml, or json, format of policy files, e.g.:
+//
+//	roles:
+//	  dealership_staff:
+//	    vehicles: [get, list, update]
+//	  inventory_admin:
+//	    vehicles: ["*"]
+//	default_roles: [dealership_staff]
+type policyFile struct {
+	// Roles maps role names to the verbs granted per resource.
+	Roles map[string]map[string][]string `json:"roles"`
+	// DefaultRoles are the roles of principals without any roles of their own.
+	DefaultRoles []string `json:"default_roles"`
+}
+
+// Policy authorizes requests by mapping the roles of their principal to permissions, i.e. verbs
+// granted per resource. Role names are case insensitive.
+type Policy struct {
+	// permissions maps role names to resources to the verbs granted.
+	permissions  map[string]map[string]map[string]bool
+	defaultRoles []string
+}
+
+// normalizeRole returns the role name as compared within the policy.
+func normalizeRole(role string) string {
+	return strings.ToLower(strings.TrimSpace(role))
+}
+
+// LoadPolicy loads the policy from the said yaml or json file.
+func LoadPolicy(file string) (*Policy, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePolicy(data)
+}
+
+// ParsePolicy parses the yaml or json policy, failing on unknown verbs and undefined default roles.
+func ParsePolicy(data []byte) (*Policy, error) {
+	content := policyFile{}
+	if err := yaml.Unmarshal(data, &content); err != nil {
+		return nil, fmt.Errorf("Invalid policy: %v", err)
+	}
+	if len(content.Roles) == 0 {
+		return nil, fmt.Errorf("Invalid policy: no roles defined")
+	}
+	policy := &Policy{permissions: map[string]map[string]map[string]bool{}}
+	for role, resources := range content.Roles {
+		name := normalizeRole(role)
+		// NB: roles of API keys are stored comma separated
+		if name == "" || strings.Contains(name, ",") {
+			return nil, fmt.Errorf("Invalid policy role name: %q", role)
+		}
+		if policy.permissions[name] == nil {
+			policy.permissions[name] = map[string]map[string]bool{}
+		}
+		for resource, verbs := range resources {
+			granted := policy.permissions[name][resource]
+			if granted == nil {
+				granted = map[string]bool{}
+				policy.permissions[name][resource] = granted
+			}
+			for _, verb := range verbs {
+				verb = strings.ToLower(verb)
+				if !isPolicyVerb(verb) {
+					return nil, fmt.Errorf("Invalid verb %q of role %s; must be one of: %s",
+						verb, role, strings.Join(policyVerbs, ", "))
+				}
+				granted[verb] = true
+			}
+		}
+	}
+	for _, role := range content.DefaultRoles {
+		name := normalizeRole(role)
+		if _, exists := policy.permissions[name]; !exists {
+			return nil, fmt.Errorf("Default role %s isn't defined", role)
+		}
+		policy.defaultRoles = append(policy.defaultRoles, name)
+	}
+	return policy, nil
+}
+
+// isPolicyVerb returns true if the verb can be granted by policies.
+func isPolicyVerb(verb string) bool {
+	for _, v := range policyVerbs {
+		if verb == v {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleNames returns the sorted names of the roles defined by the policy.
+func (policy *Policy) RoleNames() []string {
+	names := make([]string, 0, len(policy.permissions))
+	for name := range policy.permissions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Allowed returns true if any of the roles, or the default roles if there are none, grants the
+// verb on the resource.
+func (policy *Policy) Allowed(roles []string, resource, verb string) bool {
+	if len(roles) == 0 {
+		roles = policy.defaultRoles
+	}
+	for _, role := range roles {
+		resources := policy.permissions[normalizeRole(role)]
+		for _, name := range []string{resource, PolicyWildcard} {
+			if granted := resources[name]; granted[verb] || granted[PolicyWildcard] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Authorize checks the principal of the context is allowed the verb on the resource, logging the
+// decision, and returns a StoreError if denied. All requests are allowed if the policy is nil.
+func (policy *Policy) Authorize(ctx context.Context, resource, verb string) *StoreError {
+	if policy == nil {
+		return nil
+	}
+	var roles []string
+	principal := PrincipalFromContext(ctx)
+	if principal != nil {
+		roles = principal.Roles
+	}
+	// NB: requests are authenticated before being authorized so unauthenticated requests are only
+	// seen if authentication is disabled, which is refused when loading a policy
+	allowed := principal != nil && policy.Allowed(roles, resource, verb)
+
+	logger := log.FromContext(ctx)
+	event := logger.Debug()
+	if !allowed {
+		event = logger.Warn()
+	}
+	event.Str(log.Resource, resource).
+		Str(log.Verb, verb).
+		Strs(log.Roles, roles).
+		Bool(log.Allowed, allowed).
+		Msg("Authorization decision")
+	if !allowed {
+		return &StoreError{
+			Error:      fmt.Errorf("%w to %s %s", ErrPermissionDenied, verb, resource),
+			StatusCode: http.StatusForbidden,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,367 @@
+package svr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/bodenr/vehicle-api/svr/proto"
+	v2 "github.com/bodenr/vehicle-api/svr/proto/v2"
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const testPolicy = `
+roles:
+  Dealership_Staff:
+    vehicles: [get, list, update]
+  inventory_admin:
+    vehicles: ["*"]
+  auditor:
+    "*": [GET, list]
+  reader:
+    vehicles: [get, list]
+default_roles: [reader]
+`
+
+func parseTestPolicy(t *testing.T) *Policy {
+	policy, err := ParsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return policy
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		err    string
+	}{
+		{"yaml", testPolicy, ""},
+		{"json", `{"roles":{"reader":{"vehicles":["get"]}},"default_roles":["READER"]}`, ""},
+		{"unknown verb", "roles:\n  reader:\n    vehicles: [get, read]\n", `Invalid verb "read"`},
+		{"no roles", "default_roles: [reader]\n", "no roles defined"},
+		{"undefined default role", "roles:\n  reader:\n    vehicles: [get]\ndefault_roles: [writer]\n",
+			"Default role writer isn't defined"},
+		{"empty role name", "roles:\n  \" \":\n    vehicles: [get]\n", "Invalid policy role name"},
+		{"comma in role name", "roles:\n  \"a,b\":\n    vehicles: [get]\n", "Invalid policy role name"},
+		{"malformed", "roles: [", "Invalid policy"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParsePolicy([]byte(test.policy))
+			if test.err == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			} else if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+				t.Fatalf("expected error containing %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestPolicyRoleNames(t *testing.T) {
+	names := strings.Join(parseTestPolicy(t).RoleNames(), ",")
+	if names != "auditor,dealership_staff,inventory_admin,reader" {
+		t.Fatalf("unexpected role names: %s", names)
+	}
+}
+
+func TestPolicyAllowed(t *testing.T) {
+	policy := parseTestPolicy(t)
+	tests := []struct {
+		name     string
+		roles    []string
+		resource string
+		verb     string
+		allowed  bool
+	}{
+		{"granted verb", []string{"dealership_staff"}, "vehicles", VerbUpdate, true},
+		{"missing verb", []string{"dealership_staff"}, "vehicles", VerbDelete, false},
+		{"wildcard verb", []string{"inventory_admin"}, "vehicles", VerbDelete, true},
+		{"wildcard verb of other resource", []string{"inventory_admin"}, "dealers", VerbGet, false},
+		{"wildcard resource", []string{"auditor"}, "dealers", VerbGet, true},
+		{"wildcard resource missing verb", []string{"auditor"}, "dealers", VerbCreate, false},
+		{"upper case verb in policy", []string{"auditor"}, "vehicles", VerbGet, true},
+		{"role name case insensitive", []string{" DEALERSHIP_STAFF "}, "vehicles", VerbList, true},
+		{"any role grants", []string{"unknown", "inventory_admin"}, "vehicles", VerbCreate, true},
+		{"unknown role", []string{"unknown"}, "vehicles", VerbGet, false},
+		{"default roles", nil, "vehicles", VerbGet, true},
+		{"default roles missing verb", nil, "vehicles", VerbCreate, false},
+		{"default roles not added to roles", []string{"unknown"}, "vehicles", VerbGet, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if allowed := policy.Allowed(test.roles, test.resource, test.verb); allowed != test.allowed {
+				t.Fatalf("expected allowed %t, got %t", test.allowed, allowed)
+			}
+		})
+	}
+
+	noDefaults, err := ParsePolicy([]byte("roles:\n  reader:\n    vehicles: [get]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if noDefaults.Allowed(nil, "vehicles", VerbGet) {
+		t.Fatal("expected principals without roles to be denied without default roles")
+	}
+}
+
+func TestPolicyAuthorize(t *testing.T) {
+	policy := parseTestPolicy(t)
+	reader := WithPrincipal(context.Background(), &Principal{Subject: "key-1", Roles: []string{"reader"}})
+
+	var nilPolicy *Policy
+	if sErr := nilPolicy.Authorize(context.Background(), "vehicles", VerbDelete); sErr != nil {
+		t.Fatalf("expected a nil policy to allow all requests, got %v", sErr.Error)
+	}
+	if sErr := policy.Authorize(reader, "vehicles", VerbGet); sErr != nil {
+		t.Fatalf("expected get to be allowed, got %v", sErr.Error)
+	}
+	sErr := policy.Authorize(reader, "vehicles", VerbDelete)
+	if sErr == nil || sErr.StatusCode != http.StatusForbidden || !errors.Is(sErr.Error, ErrPermissionDenied) {
+		t.Fatalf("expected delete to be denied, got %v", sErr)
+	}
+	if sErr = policy.Authorize(context.Background(), "vehicles", VerbGet); sErr == nil {
+		t.Fatal("expected requests without a principal to be denied")
+	}
+}
+
+// policyTestResource is a StoredResource recording the operations reaching the store, which all
+// fail as not found.
+type policyTestResource struct {
+	StoredResource
+	calls []string
+}
+
+func (resource *policyTestResource) Name() string {
+	return "vehicles"
+}
+
+func (resource *policyTestResource) notFound(call string) *StoreError {
+	resource.calls = append(resource.calls, call)
+	return &StoreError{Error: errors.New("Not found"), StatusCode: http.StatusNotFound}
+}
+
+func (resource *policyTestResource) Search(context.Context, url.Values) ([]interface{}, *StoreError) {
+	return nil, resource.notFound("search")
+}
+
+func (resource *policyTestResource) List(context.Context) ([]interface{}, *StoreError) {
+	return nil, resource.notFound("list")
+}
+
+func (resource *policyTestResource) Get(context.Context, RequestVars) (interface{}, *StoreError) {
+	return nil, resource.notFound("get")
+}
+
+func (resource *policyTestResource) Delete(context.Context, RequestVars, string) *StoreError {
+	return resource.notFound("delete")
+}
+
+func (resource *policyTestResource) Create(context.Context, interface{}) (interface{}, *StoreError) {
+	return nil, resource.notFound("create")
+}
+
+func (resource *policyTestResource) Update(context.Context, interface{}, RequestVars, string) (interface{}, *StoreError) {
+	return nil, resource.notFound("update")
+}
+
+func (resource *policyTestResource) Unmarshal(string, []byte) (interface{}, error) {
+	return proto.Vehicle{Vin: "vin-1"}, nil
+}
+
+func (resource *policyTestResource) Validate(interface{}, string) error {
+	return nil
+}
+
+// policyTestCases are the requests of each verb checked for a read-only role.
+var policyTestCases = []struct {
+	verb    string
+	allowed bool
+}{
+	{VerbGet, true},
+	{VerbList, true},
+	{VerbCreate, false},
+	{VerbUpdate, false},
+	{VerbDelete, false},
+}
+
+// readerContext returns a context of a principal with the read-only role of the test policy.
+func readerContext() context.Context {
+	return WithPrincipal(context.Background(), &Principal{Subject: "key-1", Roles: []string{"Reader"}})
+}
+
+// checkStoreReached checks the request reached the store if and only if it was allowed.
+func checkStoreReached(t *testing.T, resource *policyTestResource, allowed bool) {
+	if reached := len(resource.calls) > 0; reached != allowed {
+		t.Fatalf("expected the store to be reached %t, got calls %v", allowed, resource.calls)
+	}
+}
+
+// policyTestRoutes are the REST requests of each verb.
+var policyTestRoutes = map[string]struct {
+	method string
+	path   string
+}{
+	VerbGet:    {http.MethodGet, "/vehicles/vin-1"},
+	VerbList:   {http.MethodGet, "/vehicles"},
+	VerbCreate: {http.MethodPost, "/vehicles"},
+	VerbUpdate: {http.MethodPut, "/vehicles/vin-1"},
+	VerbDelete: {http.MethodDelete, "/vehicles/vin-1"},
+}
+
+func TestPolicyRest(t *testing.T) {
+	for _, test := range policyTestCases {
+		t.Run(test.verb, func(t *testing.T) {
+			resource := &policyTestResource{}
+			handler := RestfulResource{Resource: resource, Policy: parseTestPolicy(t)}
+			router := mux.NewRouter()
+			router.HandleFunc("/vehicles", handler.List).Methods(http.MethodGet)
+			router.HandleFunc("/vehicles", handler.Create).Methods(http.MethodPost)
+			router.HandleFunc("/vehicles/{vin}", handler.Get).Methods(http.MethodGet)
+			router.HandleFunc("/vehicles/{vin}", handler.Update).Methods(http.MethodPut)
+			router.HandleFunc("/vehicles/{vin}", handler.Delete).Methods(http.MethodDelete)
+
+			route := policyTestRoutes[test.verb]
+			request := httptest.NewRequest(route.method, route.path, strings.NewReader(`{"vin":"vin-1"}`)).
+				WithContext(readerContext())
+			request.Header.Set("Content-Type", ContentAppJSON)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			expected := http.StatusNotFound
+			if !test.allowed {
+				expected = http.StatusForbidden
+			}
+			if recorder.Code != expected {
+				t.Fatalf("expected status %d, got %d: %s", expected, recorder.Code, recorder.Body.String())
+			}
+			checkStoreReached(t, resource, test.allowed)
+		})
+	}
+}
+
+// policyTestStream is a GRPC server stream of the said context that sends nothing.
+type policyTestStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *policyTestStream) Context() context.Context {
+	return stream.ctx
+}
+
+func (stream *policyTestStream) Send(interface{}) error {
+	return nil
+}
+
+// policyTestStreamV1 is a vehicle GRPC server stream.
+type policyTestStreamV1 struct {
+	*policyTestStream
+}
+
+func (stream policyTestStreamV1) Send(vehicle *proto.Vehicle) error {
+	return stream.policyTestStream.Send(vehicle)
+}
+
+// policyTestStreamV2 is a vehicle.v2 GRPC server stream.
+type policyTestStreamV2 struct {
+	*policyTestStream
+}
+
+func (stream policyTestStreamV2) Send(vehicle *v2.VehicleResource) error {
+	return stream.policyTestStream.Send(vehicle)
+}
+
+// checkGrpcStatus checks the GRPC error is PermissionDenied if denied, or else the NotFound of the
+// store.
+func checkGrpcStatus(t *testing.T, err error, allowed bool) {
+	expected := codes.NotFound
+	if !allowed {
+		expected = codes.PermissionDenied
+	}
+	if code := status.Code(err); code != expected {
+		t.Fatalf("expected code %s, got %s: %v", expected, code, err)
+	}
+}
+
+func TestPolicyGrpc(t *testing.T) {
+	for _, test := range policyTestCases {
+		t.Run(test.verb, func(t *testing.T) {
+			ctx := readerContext()
+			stream := &policyTestStream{ctx: ctx}
+			calls := map[string][]func(handler *GrpcHandler, handlerV2 *GrpcHandlerV2) error{
+				VerbGet: {
+					func(handler *GrpcHandler, _ *GrpcHandlerV2) error {
+						_, err := handler.GetVehicle(ctx, &proto.VehicleVIN{Vin: "vin-1"})
+						return err
+					},
+					func(_ *GrpcHandler, handler *GrpcHandlerV2) error {
+						_, err := handler.GetVehicle(ctx, &v2.GetVehicleRequest{Vin: "vin-1"})
+						return err
+					},
+				},
+				VerbList: {
+					func(handler *GrpcHandler, _ *GrpcHandlerV2) error {
+						return handler.ListVehicles(&proto.EmptyMessage{}, policyTestStreamV1{stream})
+					},
+					func(handler *GrpcHandler, _ *GrpcHandlerV2) error {
+						return handler.SearchVehicles(&proto.VehicleQuery{Query: "make=vw"}, policyTestStreamV1{stream})
+					},
+					func(_ *GrpcHandler, handler *GrpcHandlerV2) error {
+						return handler.ListVehicles(&v2.ListVehiclesRequest{}, policyTestStreamV2{stream})
+					},
+					func(_ *GrpcHandler, handler *GrpcHandlerV2) error {
+						return handler.SearchVehicles(&v2.SearchVehiclesRequest{Query: "make=vw"}, policyTestStreamV2{stream})
+					},
+				},
+				VerbCreate: {
+					func(handler *GrpcHandler, _ *GrpcHandlerV2) error {
+						_, err := handler.CreateVehicle(ctx, &proto.Vehicle{Vin: "vin-1"})
+						return err
+					},
+					func(_ *GrpcHandler, handler *GrpcHandlerV2) error {
+						_, err := handler.CreateVehicle(ctx, &v2.CreateVehicleRequest{Vin: "vin-1"})
+						return err
+					},
+				},
+				VerbUpdate: {
+					func(handler *GrpcHandler, _ *GrpcHandlerV2) error {
+						_, err := handler.UpdateVehicle(ctx, &proto.Vehicle{Vin: "vin-1"})
+						return err
+					},
+					func(_ *GrpcHandler, handler *GrpcHandlerV2) error {
+						_, err := handler.UpdateVehicle(ctx, &v2.UpdateVehicleRequest{Vin: "vin-1"})
+						return err
+					},
+				},
+				VerbDelete: {
+					func(handler *GrpcHandler, _ *GrpcHandlerV2) error {
+						_, err := handler.DeleteVehicle(ctx, &proto.VehicleVIN{Vin: "vin-1"})
+						return err
+					},
+					func(_ *GrpcHandler, handler *GrpcHandlerV2) error {
+						_, err := handler.DeleteVehicle(ctx, &v2.DeleteVehicleRequest{Vin: "vin-1"})
+						return err
+					},
+				},
+			}[test.verb]
+
+			for _, call := range calls {
+				resource := &policyTestResource{}
+				policy := parseTestPolicy(t)
+				err := call(&GrpcHandler{Resource: resource, Policy: policy},
+					&GrpcHandlerV2{Resource: resource, Policy: policy})
+				checkGrpcStatus(t, err, test.allowed)
+				checkStoreReached(t, resource, test.allowed)
+			}
+		})
+	}
+}
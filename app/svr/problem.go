@@ -0,0 +1,64 @@
+package svr
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bodenr/vehicle-api/svr/proto"
+	"github.com/bodenr/vehicle-api/validation"
+	"github.com/rs/zerolog/hlog"
+)
+
+const (
+	// ContentAppProblemJSON content type for RFC 7807 problem details in json.
+	ContentAppProblemJSON = "application/problem+json"
+
+	// ContentAppProblemXML content type for RFC 7807 problem details in xml.
+	ContentAppProblemXML = "application/problem+xml"
+
+	// ProblemTypeDefault is the RFC 7807 problem type used when the status code is self explanatory.
+	ProblemTypeDefault = "about:blank"
+)
+
+// NewProblem creates a Problem for the said request, status code and optional error.
+func NewProblem(request *http.Request, code int, err error) *proto.Problem {
+	problem := &proto.Problem{
+		Type:     ProblemTypeDefault,
+		Title:    http.StatusText(code),
+		Status:   int32(code),
+		Instance: request.URL.Path,
+	}
+	if err != nil {
+		problem.Detail = err.Error()
+	}
+	if id, ok := hlog.IDFromRequest(request); ok {
+		problem.RequestId = id.String()
+	}
+	var violations validation.Errors
+	if errors.As(err, &violations) {
+		for _, violation := range violations {
+			problem.Errors = append(problem.Errors, &proto.FieldViolation{
+				Field:   violation.Field,
+				Message: violation.Message,
+				Rule:    violation.Rule,
+			})
+		}
+	}
+	return problem
+}
+
+// ProblemContentType returns the problem details content type for the said negotiated content type.
+// Content types without a problem details variant are returned as is.
+func ProblemContentType(contentType string) string {
+	reg := registry.lookup(contentType)
+	if reg == nil {
+		return contentType
+	}
+	switch reg.mediaType {
+	case ContentAppJSON:
+		return ContentAppProblemJSON
+	case ContentAppXML:
+		return ContentAppProblemXML
+	}
+	return contentType
+}
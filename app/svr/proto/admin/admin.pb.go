@@ -0,0 +1,295 @@
+// Code generated by protoc-gen-gogo. DO NOT EDIT.
+// source: admin/admin.proto
+
+package admin
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/gogo/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
+
+type GetLogLevelRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetLogLevelRequest) Reset()         { *m = GetLogLevelRequest{} }
+func (m *GetLogLevelRequest) String() string { return proto.CompactTextString(m) }
+func (*GetLogLevelRequest) ProtoMessage()    {}
+func (*GetLogLevelRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8595c8dce2486799, []int{0}
+}
+func (m *GetLogLevelRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetLogLevelRequest.Unmarshal(m, b)
+}
+func (m *GetLogLevelRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetLogLevelRequest.Marshal(b, m, deterministic)
+}
+func (m *GetLogLevelRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetLogLevelRequest.Merge(m, src)
+}
+func (m *GetLogLevelRequest) XXX_Size() int {
+	return xxx_messageInfo_GetLogLevelRequest.Size(m)
+}
+func (m *GetLogLevelRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetLogLevelRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetLogLevelRequest proto.InternalMessageInfo
+
+// SetLogLevelRequest changes the default level when set and the level of each package in packages;
+// an empty package level removes its override.
+type SetLogLevelRequest struct {
+	Level                string            `protobuf:"bytes,1,opt,name=level,proto3" json:"level,omitempty"`
+	Packages             map[string]string `protobuf:"bytes,2,rep,name=packages,proto3" json:"packages,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *SetLogLevelRequest) Reset()         { *m = SetLogLevelRequest{} }
+func (m *SetLogLevelRequest) String() string { return proto.CompactTextString(m) }
+func (*SetLogLevelRequest) ProtoMessage()    {}
+func (*SetLogLevelRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8595c8dce2486799, []int{1}
+}
+func (m *SetLogLevelRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetLogLevelRequest.Unmarshal(m, b)
+}
+func (m *SetLogLevelRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetLogLevelRequest.Marshal(b, m, deterministic)
+}
+func (m *SetLogLevelRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetLogLevelRequest.Merge(m, src)
+}
+func (m *SetLogLevelRequest) XXX_Size() int {
+	return xxx_messageInfo_SetLogLevelRequest.Size(m)
+}
+func (m *SetLogLevelRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetLogLevelRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetLogLevelRequest proto.InternalMessageInfo
+
+func (m *SetLogLevelRequest) GetLevel() string {
+	if m != nil {
+		return m.Level
+	}
+	return ""
+}
+
+func (m *SetLogLevelRequest) GetPackages() map[string]string {
+	if m != nil {
+		return m.Packages
+	}
+	return nil
+}
+
+// LogLevels holds the default log level and the per package overrides.
+type LogLevels struct {
+	Level                string            `protobuf:"bytes,1,opt,name=level,proto3" json:"level,omitempty"`
+	Packages             map[string]string `protobuf:"bytes,2,rep,name=packages,proto3" json:"packages,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *LogLevels) Reset()         { *m = LogLevels{} }
+func (m *LogLevels) String() string { return proto.CompactTextString(m) }
+func (*LogLevels) ProtoMessage()    {}
+func (*LogLevels) Descriptor() ([]byte, []int) {
+	return fileDescriptor_8595c8dce2486799, []int{2}
+}
+func (m *LogLevels) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LogLevels.Unmarshal(m, b)
+}
+func (m *LogLevels) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LogLevels.Marshal(b, m, deterministic)
+}
+func (m *LogLevels) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LogLevels.Merge(m, src)
+}
+func (m *LogLevels) XXX_Size() int {
+	return xxx_messageInfo_LogLevels.Size(m)
+}
+func (m *LogLevels) XXX_DiscardUnknown() {
+	xxx_messageInfo_LogLevels.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LogLevels proto.InternalMessageInfo
+
+func (m *LogLevels) GetLevel() string {
+	if m != nil {
+		return m.Level
+	}
+	return ""
+}
+
+func (m *LogLevels) GetPackages() map[string]string {
+	if m != nil {
+		return m.Packages
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*GetLogLevelRequest)(nil), "admin.GetLogLevelRequest")
+	proto.RegisterType((*SetLogLevelRequest)(nil), "admin.SetLogLevelRequest")
+	proto.RegisterMapType((map[string]string)(nil), "admin.SetLogLevelRequest.PackagesEntry")
+	proto.RegisterType((*LogLevels)(nil), "admin.LogLevels")
+	proto.RegisterMapType((map[string]string)(nil), "admin.LogLevels.PackagesEntry")
+}
+
+func init() { proto.RegisterFile("admin/admin.proto", fileDescriptor_8595c8dce2486799) }
+
+var fileDescriptor_8595c8dce2486799 = []byte{
+	// 230 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x12, 0x4c, 0x4c, 0xc9, 0xcd,
+	0xcc, 0xd3, 0x07, 0x93, 0x7a, 0x05, 0x45, 0xf9, 0x25, 0xf9, 0x42, 0xac, 0x60, 0x8e, 0x92, 0x08,
+	0x97, 0x90, 0x7b, 0x6a, 0x89, 0x4f, 0x7e, 0xba, 0x4f, 0x6a, 0x59, 0x6a, 0x4e, 0x50, 0x6a, 0x61,
+	0x69, 0x6a, 0x71, 0x89, 0xd2, 0x1a, 0x46, 0x2e, 0xa1, 0x60, 0x0c, 0x61, 0x21, 0x11, 0x2e, 0xd6,
+	0x1c, 0x10, 0x5f, 0x82, 0x51, 0x81, 0x51, 0x83, 0x33, 0x08, 0xc2, 0x11, 0x72, 0xe6, 0xe2, 0x28,
+	0x48, 0x4c, 0xce, 0x4e, 0x4c, 0x4f, 0x2d, 0x96, 0x60, 0x52, 0x60, 0xd6, 0xe0, 0x36, 0x52, 0xd7,
+	0x83, 0xd8, 0x84, 0x69, 0x84, 0x5e, 0x00, 0x54, 0xa5, 0x6b, 0x5e, 0x49, 0x51, 0x65, 0x10, 0x5c,
+	0xa3, 0x94, 0x35, 0x17, 0x2f, 0x8a, 0x94, 0x90, 0x00, 0x17, 0x73, 0x76, 0x6a, 0x25, 0xd4, 0x26,
+	0x10, 0x13, 0x64, 0x7b, 0x59, 0x62, 0x4e, 0x69, 0xaa, 0x04, 0x13, 0xc4, 0x76, 0x30, 0xc7, 0x8a,
+	0xc9, 0x82, 0x51, 0x69, 0x16, 0x23, 0x17, 0x27, 0xcc, 0xa2, 0x62, 0x1c, 0xae, 0xb4, 0xc2, 0x70,
+	0xa5, 0x1c, 0xd4, 0x95, 0x70, 0x9d, 0x34, 0x71, 0x9c, 0x51, 0x1b, 0x23, 0x17, 0x87, 0x4f, 0x7e,
+	0xba, 0x23, 0xc8, 0x2e, 0x21, 0x1b, 0x2e, 0x6e, 0xa4, 0xe0, 0x16, 0x92, 0x84, 0x3a, 0x01, 0x33,
+	0x0a, 0xa4, 0x04, 0xd0, 0x5d, 0xa7, 0xc4, 0x00, 0xd2, 0x1d, 0x8c, 0x45, 0x77, 0x30, 0x51, 0xba,
+	0x9d, 0xd8, 0xa3, 0x20, 0x71, 0x9e, 0xc4, 0x06, 0x4e, 0x01, 0xc6, 0x80, 0x01, 0x00, 0xc8, 0x45,
+	0x01, 0xb3, 0x16, 0x02, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// LogAdminClient is the client API for LogAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type LogAdminClient interface {
+	GetLogLevel(ctx context.Context, in *GetLogLevelRequest, opts ...grpc.CallOption) (*LogLevels, error)
+	SetLogLevel(ctx context.Context, in *SetLogLevelRequest, opts ...grpc.CallOption) (*LogLevels, error)
+}
+
+type logAdminClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewLogAdminClient(cc *grpc.ClientConn) LogAdminClient {
+	return &logAdminClient{cc}
+}
+
+func (c *logAdminClient) GetLogLevel(ctx context.Context, in *GetLogLevelRequest, opts ...grpc.CallOption) (*LogLevels, error) {
+	out := new(LogLevels)
+	err := c.cc.Invoke(ctx, "/admin.LogAdmin/GetLogLevel", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *logAdminClient) SetLogLevel(ctx context.Context, in *SetLogLevelRequest, opts ...grpc.CallOption) (*LogLevels, error) {
+	out := new(LogLevels)
+	err := c.cc.Invoke(ctx, "/admin.LogAdmin/SetLogLevel", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LogAdminServer is the server API for LogAdmin service.
+type LogAdminServer interface {
+	GetLogLevel(context.Context, *GetLogLevelRequest) (*LogLevels, error)
+	SetLogLevel(context.Context, *SetLogLevelRequest) (*LogLevels, error)
+}
+
+// UnimplementedLogAdminServer can be embedded to have forward compatible implementations.
+type UnimplementedLogAdminServer struct {
+}
+
+func (*UnimplementedLogAdminServer) GetLogLevel(ctx context.Context, req *GetLogLevelRequest) (*LogLevels, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLogLevel not implemented")
+}
+func (*UnimplementedLogAdminServer) SetLogLevel(ctx context.Context, req *SetLogLevelRequest) (*LogLevels, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLogLevel not implemented")
+}
+
+func RegisterLogAdminServer(s *grpc.Server, srv LogAdminServer) {
+	s.RegisterService(&_LogAdmin_serviceDesc, srv)
+}
+
+func _LogAdmin_GetLogLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLogLevelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LogAdminServer).GetLogLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/admin.LogAdmin/GetLogLevel",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LogAdminServer).GetLogLevel(ctx, req.(*GetLogLevelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LogAdmin_SetLogLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLogLevelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LogAdminServer).SetLogLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/admin.LogAdmin/SetLogLevel",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LogAdminServer).SetLogLevel(ctx, req.(*SetLogLevelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _LogAdmin_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "admin.LogAdmin",
+	HandlerType: (*LogAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetLogLevel",
+			Handler:    _LogAdmin_GetLogLevel_Handler,
+		},
+		{
+			MethodName: "SetLogLevel",
+			Handler:    _LogAdmin_SetLogLevel_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "admin/admin.proto",
+}
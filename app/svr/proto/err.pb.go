@@ -21,11 +21,9 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
 
+// only used for protobuf over rest/http
 type ErrorResponse struct {
-	Message              string   `protobuf:"bytes,1,opt,name=Message,proto3" json:"error_message" xml:"error_message"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Message string `protobuf:"bytes,1,opt,name=Message,proto3" json:"error_message" xml:"error_message"`
 }
 
 func (m *ErrorResponse) Reset()         { *m = ErrorResponse{} }
@@ -59,31 +57,218 @@ func (m *ErrorResponse) GetMessage() string {
 	return ""
 }
 
+// Problem is a RFC 7807 problem details error response; only used over rest/http.
+type Problem struct {
+	Type      string            `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty" xml:"type,omitempty"`
+	Title     string            `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty" xml:"title,omitempty"`
+	Status    int32             `protobuf:"varint,3,opt,name=status,proto3" json:"status,omitempty" xml:"status,omitempty"`
+	Detail    string            `protobuf:"bytes,4,opt,name=detail,proto3" json:"detail,omitempty" xml:"detail,omitempty"`
+	Instance  string            `protobuf:"bytes,5,opt,name=instance,proto3" json:"instance,omitempty" xml:"instance,omitempty"`
+	RequestId string            `protobuf:"bytes,6,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty" xml:"request_id,omitempty"`
+	Errors    []*FieldViolation `protobuf:"bytes,7,rep,name=errors,proto3" json:"errors,omitempty" xml:"errors>i,omitempty"`
+}
+
+func (m *Problem) Reset()         { *m = Problem{} }
+func (m *Problem) String() string { return proto.CompactTextString(m) }
+func (*Problem) ProtoMessage()    {}
+func (*Problem) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b4a1db73bc95ee8c, []int{1}
+}
+func (m *Problem) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Problem.Unmarshal(m, b)
+}
+func (m *Problem) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Problem.Marshal(b, m, deterministic)
+}
+func (m *Problem) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Problem.Merge(m, src)
+}
+func (m *Problem) XXX_Size() int {
+	return xxx_messageInfo_Problem.Size(m)
+}
+func (m *Problem) XXX_DiscardUnknown() {
+	xxx_messageInfo_Problem.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Problem proto.InternalMessageInfo
+
+func (m *Problem) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *Problem) GetTitle() string {
+	if m != nil {
+		return m.Title
+	}
+	return ""
+}
+
+func (m *Problem) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+func (m *Problem) GetDetail() string {
+	if m != nil {
+		return m.Detail
+	}
+	return ""
+}
+
+func (m *Problem) GetInstance() string {
+	if m != nil {
+		return m.Instance
+	}
+	return ""
+}
+
+func (m *Problem) GetRequestId() string {
+	if m != nil {
+		return m.RequestId
+	}
+	return ""
+}
+
+func (m *Problem) GetErrors() []*FieldViolation {
+	if m != nil {
+		return m.Errors
+	}
+	return nil
+}
+
+// FieldViolation describes an invalid field of a request.
+type FieldViolation struct {
+	Field   string `protobuf:"bytes,1,opt,name=field,proto3" json:"field,omitempty" xml:"field"`
+	Message string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty" xml:"message"`
+	Rule    string `protobuf:"bytes,3,opt,name=rule,proto3" json:"rule,omitempty" xml:"rule,omitempty"`
+}
+
+func (m *FieldViolation) Reset()         { *m = FieldViolation{} }
+func (m *FieldViolation) String() string { return proto.CompactTextString(m) }
+func (*FieldViolation) ProtoMessage()    {}
+func (*FieldViolation) Descriptor() ([]byte, []int) {
+	return fileDescriptor_b4a1db73bc95ee8c, []int{2}
+}
+func (m *FieldViolation) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FieldViolation.Unmarshal(m, b)
+}
+func (m *FieldViolation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FieldViolation.Marshal(b, m, deterministic)
+}
+func (m *FieldViolation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FieldViolation.Merge(m, src)
+}
+func (m *FieldViolation) XXX_Size() int {
+	return xxx_messageInfo_FieldViolation.Size(m)
+}
+func (m *FieldViolation) XXX_DiscardUnknown() {
+	xxx_messageInfo_FieldViolation.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FieldViolation proto.InternalMessageInfo
+
+func (m *FieldViolation) GetField() string {
+	if m != nil {
+		return m.Field
+	}
+	return ""
+}
+
+func (m *FieldViolation) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m *FieldViolation) GetRule() string {
+	if m != nil {
+		return m.Rule
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*ErrorResponse)(nil), "err.ErrorResponse")
+	proto.RegisterType((*Problem)(nil), "err.Problem")
+	proto.RegisterType((*FieldViolation)(nil), "err.FieldViolation")
 }
 
 func init() { proto.RegisterFile("err.proto", fileDescriptor_b4a1db73bc95ee8c) }
 
 var fileDescriptor_b4a1db73bc95ee8c = []byte{
-	// 147 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x4c, 0x2d, 0x2a, 0xd2,
-	0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x4e, 0x2d, 0x2a, 0x92, 0xd2, 0x4d, 0xcf, 0x2c, 0xc9,
-	0x28, 0x4d, 0xd2, 0x4b, 0xce, 0xcf, 0xd5, 0x4f, 0xcf, 0x4f, 0xcf, 0xd7, 0x07, 0xcb, 0x25, 0x95,
-	0xa6, 0x81, 0x79, 0x60, 0x0e, 0x98, 0x05, 0xd1, 0xa3, 0x14, 0xcc, 0xc5, 0xeb, 0x5a, 0x54, 0x94,
-	0x5f, 0x14, 0x94, 0x5a, 0x5c, 0x90, 0x9f, 0x57, 0x9c, 0x2a, 0xe4, 0xc4, 0xc5, 0xee, 0x9b, 0x5a,
-	0x5c, 0x9c, 0x98, 0x9e, 0x2a, 0xc1, 0xa8, 0xc0, 0xa8, 0xc1, 0xe9, 0xa4, 0xf1, 0xea, 0x9e, 0x3c,
-	0x6f, 0x2a, 0x48, 0x4d, 0x7c, 0x2e, 0x44, 0xe2, 0xd3, 0x3d, 0x79, 0xe1, 0x8a, 0xdc, 0x1c, 0x2b,
-	0x25, 0x14, 0x51, 0xa5, 0x20, 0x98, 0x46, 0x27, 0xee, 0x1f, 0x0f, 0xe5, 0x18, 0xa3, 0x58, 0x21,
-	0x36, 0xb3, 0x81, 0x29, 0x63, 0x40, 0x00, 0x00, 0x00, 0xff, 0xff, 0x29, 0x5f, 0x90, 0x05, 0xa9,
-	0x00, 0x00, 0x00,
+	// 427 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x5c, 0x92, 0x4d, 0x8b, 0xd3, 0x40,
+	0x18, 0xc7, 0x1b, 0xb3, 0x69, 0xed, 0x2c, 0x55, 0x9c, 0xf5, 0x65, 0x2c, 0x92, 0x29, 0x01, 0xa1,
+	0xe0, 0xda, 0xc2, 0x7a, 0x11, 0x41, 0x0f, 0x05, 0x85, 0x3d, 0x08, 0x52, 0xc1, 0x83, 0x97, 0x25,
+	0xdd, 0x3e, 0x5b, 0x07, 0x92, 0x4e, 0x9c, 0x99, 0x80, 0xfb, 0x2d, 0x44, 0xfc, 0x60, 0x1e, 0x3d,
+	0x0d, 0x48, 0x6f, 0x1e, 0xe7, 0xe4, 0x51, 0xe6, 0x99, 0xd4, 0x26, 0x9e, 0x32, 0xf3, 0x7b, 0xfe,
+	0xbf, 0xc9, 0xcb, 0x3f, 0x64, 0x08, 0x4a, 0xcd, 0x2a, 0x25, 0x8d, 0xa4, 0x31, 0x28, 0x35, 0x7e,
+	0xba, 0x11, 0xe6, 0x53, 0xbd, 0x9a, 0x5d, 0xca, 0x72, 0xbe, 0x91, 0x1b, 0x39, 0xc7, 0xd9, 0xaa,
+	0xbe, 0xc2, 0x1d, 0x6e, 0x70, 0x15, 0x9c, 0xec, 0x3d, 0x19, 0xbd, 0x56, 0x4a, 0xaa, 0x25, 0xe8,
+	0x4a, 0x6e, 0x35, 0xd0, 0x05, 0x19, 0xbc, 0x05, 0xad, 0xf3, 0x0d, 0xb0, 0x68, 0x12, 0x4d, 0x87,
+	0x8b, 0xe9, 0x6f, 0xcb, 0x47, 0xe0, 0x33, 0x17, 0x65, 0x18, 0x38, 0xcb, 0x4f, 0xbe, 0x94, 0xc5,
+	0x8b, 0xac, 0x43, 0xb3, 0xe5, 0x5e, 0xcc, 0xbe, 0xc7, 0x64, 0xf0, 0x4e, 0xc9, 0x55, 0x01, 0x25,
+	0x3d, 0x25, 0x47, 0xe6, 0xba, 0xda, 0x1f, 0xc6, 0x9c, 0xe5, 0x77, 0xd1, 0xf5, 0xf0, 0x54, 0x96,
+	0xc2, 0x40, 0x59, 0x99, 0xeb, 0x6c, 0x89, 0x29, 0x3a, 0x27, 0x89, 0x11, 0xa6, 0x00, 0x76, 0x03,
+	0xe3, 0x0f, 0x9d, 0xe5, 0xf7, 0x42, 0xdc, 0xd3, 0x76, 0x3e, 0xe4, 0xe8, 0x19, 0xe9, 0x6b, 0x93,
+	0x9b, 0x5a, 0xb3, 0x78, 0x12, 0x4d, 0x93, 0xc5, 0xd8, 0x59, 0x7e, 0x1f, 0x8d, 0x80, 0xdb, 0x4a,
+	0x93, 0xf4, 0xce, 0x1a, 0x4c, 0x2e, 0x0a, 0x76, 0x84, 0x77, 0x39, 0x38, 0x01, 0x77, 0x9c, 0x80,
+	0xe8, 0x73, 0x72, 0x53, 0x6c, 0xb5, 0xc9, 0xb7, 0x97, 0xc0, 0x12, 0xb4, 0x1e, 0x39, 0xcb, 0x19,
+	0x5a, 0xfb, 0x41, 0xdb, 0xfb, 0x97, 0xa6, 0x2f, 0x09, 0x51, 0xf0, 0xb9, 0x06, 0x6d, 0x2e, 0xc4,
+	0x9a, 0xf5, 0xd1, 0x4d, 0x9d, 0xe5, 0x63, 0x74, 0x0f, 0xa3, 0xb6, 0x3d, 0x6c, 0xf0, 0xf9, 0x9a,
+	0x9e, 0x93, 0x3e, 0x7e, 0x66, 0xcd, 0x06, 0x93, 0x78, 0x7a, 0x7c, 0x76, 0x32, 0xf3, 0x85, 0xbf,
+	0x11, 0x50, 0xac, 0x3f, 0x08, 0x59, 0xe4, 0x46, 0xc8, 0x6d, 0xeb, 0x59, 0x42, 0xf6, 0x95, 0xe8,
+	0xbc, 0x43, 0x80, 0xd9, 0xb7, 0x88, 0xdc, 0xea, 0x8a, 0xf4, 0x31, 0x49, 0xae, 0x3c, 0x69, 0xea,
+	0xb9, 0xed, 0x2c, 0x3f, 0xc6, 0x73, 0x90, 0x66, 0xcb, 0x30, 0xa5, 0x4f, 0xc8, 0xa0, 0x69, 0xb9,
+	0x29, 0xe6, 0x8e, 0xb3, 0x7c, 0x84, 0xc1, 0x43, 0xfb, 0xcd, 0xca, 0x37, 0xae, 0xea, 0x02, 0x58,
+	0xfc, 0x5f, 0xe3, 0x1e, 0x76, 0x1a, 0xf7, 0x60, 0xf1, 0xe0, 0xcf, 0xaf, 0x34, 0xfa, 0xba, 0x4b,
+	0x7b, 0x3f, 0x76, 0x69, 0xef, 0xe7, 0x2e, 0xed, 0x7d, 0x4c, 0xc2, 0x1f, 0xdb, 0xc7, 0xcb, 0xb3,
+	0xbf, 0x03, 0x00, 0xdf, 0x1a, 0x00, 0xe2, 0xe1, 0x02, 0x00, 0x00,
 }
 
 func NewPopulatedErrorResponse(r randyErr, easy bool) *ErrorResponse {
 	this := &ErrorResponse{}
 	this.Message = string(randStringErr(r))
 	if !easy && r.Intn(10) != 0 {
-		this.XXX_unrecognized = randUnrecognizedErr(r, 2)
+	}
+	return this
+}
+
+func NewPopulatedProblem(r randyErr, easy bool) *Problem {
+	this := &Problem{}
+	this.Type = string(randStringErr(r))
+	this.Title = string(randStringErr(r))
+	this.Status = int32(r.Int31())
+	if r.Intn(2) == 0 {
+		this.Status *= -1
+	}
+	this.Detail = string(randStringErr(r))
+	this.Instance = string(randStringErr(r))
+	this.RequestId = string(randStringErr(r))
+	if r.Intn(5) != 0 {
+		v1 := r.Intn(5)
+		this.Errors = make([]*FieldViolation, v1)
+		for i := 0; i < v1; i++ {
+			this.Errors[i] = NewPopulatedFieldViolation(r, easy)
+		}
+	}
+	if !easy && r.Intn(10) != 0 {
+	}
+	return this
+}
+
+func NewPopulatedFieldViolation(r randyErr, easy bool) *FieldViolation {
+	this := &FieldViolation{}
+	this.Field = string(randStringErr(r))
+	this.Message = string(randStringErr(r))
+	this.Rule = string(randStringErr(r))
+	if !easy && r.Intn(10) != 0 {
 	}
 	return this
 }
@@ -107,9 +292,9 @@ func randUTF8RuneErr(r randyErr) rune {
 	return rune(ru + 61)
 }
 func randStringErr(r randyErr) string {
-	v1 := r.Intn(100)
-	tmps := make([]rune, v1)
-	for i := 0; i < v1; i++ {
+	v2 := r.Intn(100)
+	tmps := make([]rune, v2)
+	for i := 0; i < v2; i++ {
 		tmps[i] = randUTF8RuneErr(r)
 	}
 	return string(tmps)
@@ -131,11 +316,11 @@ func randFieldErr(dAtA []byte, r randyErr, fieldNumber int, wire int) []byte {
 	switch wire {
 	case 0:
 		dAtA = encodeVarintPopulateErr(dAtA, uint64(key))
-		v2 := r.Int63()
+		v3 := r.Int63()
 		if r.Intn(2) == 0 {
-			v2 *= -1
+			v3 *= -1
 		}
-		dAtA = encodeVarintPopulateErr(dAtA, uint64(v2))
+		dAtA = encodeVarintPopulateErr(dAtA, uint64(v3))
 	case 1:
 		dAtA = encodeVarintPopulateErr(dAtA, uint64(key))
 		dAtA = append(dAtA, byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)))
@@ -0,0 +1,14 @@
+package proto
+
+import "encoding/xml"
+
+// ProblemNamespace is the XML namespace of RFC 7807 problem details.
+const ProblemNamespace = "urn:ietf:rfc:7807"
+
+// MarshalXML marshals the Problem as a RFC 7807 problem element.
+func (m *Problem) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	// NB: the alias type has no methods which prevents recursing into MarshalXML
+	type problem Problem
+	start.Name = xml.Name{Space: ProblemNamespace, Local: "problem"}
+	return encoder.EncodeElement((*problem)(m), start)
+}
@@ -0,0 +1,1046 @@
+// Code generated by protoc-gen-gogo. DO NOT EDIT.
+// source: v2/vehicle.proto
+
+package v2
+
+import (
+	context "context"
+	fmt "fmt"
+	_ "github.com/gogo/protobuf/gogoproto"
+	proto "github.com/gogo/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
+
+type GetVehicleRequest struct {
+	Vin                  string   `protobuf:"bytes,1,opt,name=vin,proto3" json:"vin,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetVehicleRequest) Reset()         { *m = GetVehicleRequest{} }
+func (m *GetVehicleRequest) String() string { return proto.CompactTextString(m) }
+func (*GetVehicleRequest) ProtoMessage()    {}
+func (*GetVehicleRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_7867fac5d8e1caae, []int{0}
+}
+func (m *GetVehicleRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetVehicleRequest.Unmarshal(m, b)
+}
+func (m *GetVehicleRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetVehicleRequest.Marshal(b, m, deterministic)
+}
+func (m *GetVehicleRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetVehicleRequest.Merge(m, src)
+}
+func (m *GetVehicleRequest) XXX_Size() int {
+	return xxx_messageInfo_GetVehicleRequest.Size(m)
+}
+func (m *GetVehicleRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetVehicleRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetVehicleRequest proto.InternalMessageInfo
+
+func (m *GetVehicleRequest) GetVin() string {
+	if m != nil {
+		return m.Vin
+	}
+	return ""
+}
+
+type CreateVehicleRequest struct {
+	Vin                  string   `protobuf:"bytes,1,opt,name=vin,proto3" json:"vin,omitempty"`
+	Make                 string   `protobuf:"bytes,2,opt,name=make,proto3" json:"make,omitempty"`
+	Model                string   `protobuf:"bytes,3,opt,name=model,proto3" json:"model,omitempty"`
+	Year                 int32    `protobuf:"varint,4,opt,name=year,proto3" json:"year,omitempty"`
+	ExteriorColor        string   `protobuf:"bytes,5,opt,name=exterior_color,json=exteriorColor,proto3" json:"exterior_color,omitempty"`
+	InteriorColor        string   `protobuf:"bytes,6,opt,name=interior_color,json=interiorColor,proto3" json:"interior_color,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CreateVehicleRequest) Reset()         { *m = CreateVehicleRequest{} }
+func (m *CreateVehicleRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateVehicleRequest) ProtoMessage()    {}
+func (*CreateVehicleRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_7867fac5d8e1caae, []int{1}
+}
+func (m *CreateVehicleRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateVehicleRequest.Unmarshal(m, b)
+}
+func (m *CreateVehicleRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateVehicleRequest.Marshal(b, m, deterministic)
+}
+func (m *CreateVehicleRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateVehicleRequest.Merge(m, src)
+}
+func (m *CreateVehicleRequest) XXX_Size() int {
+	return xxx_messageInfo_CreateVehicleRequest.Size(m)
+}
+func (m *CreateVehicleRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateVehicleRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateVehicleRequest proto.InternalMessageInfo
+
+func (m *CreateVehicleRequest) GetVin() string {
+	if m != nil {
+		return m.Vin
+	}
+	return ""
+}
+
+func (m *CreateVehicleRequest) GetMake() string {
+	if m != nil {
+		return m.Make
+	}
+	return ""
+}
+
+func (m *CreateVehicleRequest) GetModel() string {
+	if m != nil {
+		return m.Model
+	}
+	return ""
+}
+
+func (m *CreateVehicleRequest) GetYear() int32 {
+	if m != nil {
+		return m.Year
+	}
+	return 0
+}
+
+func (m *CreateVehicleRequest) GetExteriorColor() string {
+	if m != nil {
+		return m.ExteriorColor
+	}
+	return ""
+}
+
+func (m *CreateVehicleRequest) GetInteriorColor() string {
+	if m != nil {
+		return m.InteriorColor
+	}
+	return ""
+}
+
+type UpdateVehicleRequest struct {
+	Vin                  string   `protobuf:"bytes,1,opt,name=vin,proto3" json:"vin,omitempty"`
+	Make                 string   `protobuf:"bytes,2,opt,name=make,proto3" json:"make,omitempty"`
+	Model                string   `protobuf:"bytes,3,opt,name=model,proto3" json:"model,omitempty"`
+	Year                 int32    `protobuf:"varint,4,opt,name=year,proto3" json:"year,omitempty"`
+	ExteriorColor        string   `protobuf:"bytes,5,opt,name=exterior_color,json=exteriorColor,proto3" json:"exterior_color,omitempty"`
+	InteriorColor        string   `protobuf:"bytes,6,opt,name=interior_color,json=interiorColor,proto3" json:"interior_color,omitempty"`
+	Etag                 string   `protobuf:"bytes,7,opt,name=etag,proto3" json:"etag,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UpdateVehicleRequest) Reset()         { *m = UpdateVehicleRequest{} }
+func (m *UpdateVehicleRequest) String() string { return proto.CompactTextString(m) }
+func (*UpdateVehicleRequest) ProtoMessage()    {}
+func (*UpdateVehicleRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_7867fac5d8e1caae, []int{2}
+}
+func (m *UpdateVehicleRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateVehicleRequest.Unmarshal(m, b)
+}
+func (m *UpdateVehicleRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateVehicleRequest.Marshal(b, m, deterministic)
+}
+func (m *UpdateVehicleRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateVehicleRequest.Merge(m, src)
+}
+func (m *UpdateVehicleRequest) XXX_Size() int {
+	return xxx_messageInfo_UpdateVehicleRequest.Size(m)
+}
+func (m *UpdateVehicleRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateVehicleRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateVehicleRequest proto.InternalMessageInfo
+
+func (m *UpdateVehicleRequest) GetVin() string {
+	if m != nil {
+		return m.Vin
+	}
+	return ""
+}
+
+func (m *UpdateVehicleRequest) GetMake() string {
+	if m != nil {
+		return m.Make
+	}
+	return ""
+}
+
+func (m *UpdateVehicleRequest) GetModel() string {
+	if m != nil {
+		return m.Model
+	}
+	return ""
+}
+
+func (m *UpdateVehicleRequest) GetYear() int32 {
+	if m != nil {
+		return m.Year
+	}
+	return 0
+}
+
+func (m *UpdateVehicleRequest) GetExteriorColor() string {
+	if m != nil {
+		return m.ExteriorColor
+	}
+	return ""
+}
+
+func (m *UpdateVehicleRequest) GetInteriorColor() string {
+	if m != nil {
+		return m.InteriorColor
+	}
+	return ""
+}
+
+func (m *UpdateVehicleRequest) GetEtag() string {
+	if m != nil {
+		return m.Etag
+	}
+	return ""
+}
+
+type DeleteVehicleRequest struct {
+	Vin                  string   `protobuf:"bytes,1,opt,name=vin,proto3" json:"vin,omitempty"`
+	Etag                 string   `protobuf:"bytes,2,opt,name=etag,proto3" json:"etag,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteVehicleRequest) Reset()         { *m = DeleteVehicleRequest{} }
+func (m *DeleteVehicleRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteVehicleRequest) ProtoMessage()    {}
+func (*DeleteVehicleRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_7867fac5d8e1caae, []int{3}
+}
+func (m *DeleteVehicleRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteVehicleRequest.Unmarshal(m, b)
+}
+func (m *DeleteVehicleRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteVehicleRequest.Marshal(b, m, deterministic)
+}
+func (m *DeleteVehicleRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteVehicleRequest.Merge(m, src)
+}
+func (m *DeleteVehicleRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteVehicleRequest.Size(m)
+}
+func (m *DeleteVehicleRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteVehicleRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteVehicleRequest proto.InternalMessageInfo
+
+func (m *DeleteVehicleRequest) GetVin() string {
+	if m != nil {
+		return m.Vin
+	}
+	return ""
+}
+
+func (m *DeleteVehicleRequest) GetEtag() string {
+	if m != nil {
+		return m.Etag
+	}
+	return ""
+}
+
+type DeleteVehicleResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteVehicleResponse) Reset()         { *m = DeleteVehicleResponse{} }
+func (m *DeleteVehicleResponse) String() string { return proto.CompactTextString(m) }
+func (*DeleteVehicleResponse) ProtoMessage()    {}
+func (*DeleteVehicleResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_7867fac5d8e1caae, []int{4}
+}
+func (m *DeleteVehicleResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteVehicleResponse.Unmarshal(m, b)
+}
+func (m *DeleteVehicleResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteVehicleResponse.Marshal(b, m, deterministic)
+}
+func (m *DeleteVehicleResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteVehicleResponse.Merge(m, src)
+}
+func (m *DeleteVehicleResponse) XXX_Size() int {
+	return xxx_messageInfo_DeleteVehicleResponse.Size(m)
+}
+func (m *DeleteVehicleResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteVehicleResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteVehicleResponse proto.InternalMessageInfo
+
+type ListVehiclesRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListVehiclesRequest) Reset()         { *m = ListVehiclesRequest{} }
+func (m *ListVehiclesRequest) String() string { return proto.CompactTextString(m) }
+func (*ListVehiclesRequest) ProtoMessage()    {}
+func (*ListVehiclesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_7867fac5d8e1caae, []int{5}
+}
+func (m *ListVehiclesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListVehiclesRequest.Unmarshal(m, b)
+}
+func (m *ListVehiclesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListVehiclesRequest.Marshal(b, m, deterministic)
+}
+func (m *ListVehiclesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListVehiclesRequest.Merge(m, src)
+}
+func (m *ListVehiclesRequest) XXX_Size() int {
+	return xxx_messageInfo_ListVehiclesRequest.Size(m)
+}
+func (m *ListVehiclesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListVehiclesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListVehiclesRequest proto.InternalMessageInfo
+
+type SearchVehiclesRequest struct {
+	Query                string   `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SearchVehiclesRequest) Reset()         { *m = SearchVehiclesRequest{} }
+func (m *SearchVehiclesRequest) String() string { return proto.CompactTextString(m) }
+func (*SearchVehiclesRequest) ProtoMessage()    {}
+func (*SearchVehiclesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_7867fac5d8e1caae, []int{6}
+}
+func (m *SearchVehiclesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SearchVehiclesRequest.Unmarshal(m, b)
+}
+func (m *SearchVehiclesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SearchVehiclesRequest.Marshal(b, m, deterministic)
+}
+func (m *SearchVehiclesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SearchVehiclesRequest.Merge(m, src)
+}
+func (m *SearchVehiclesRequest) XXX_Size() int {
+	return xxx_messageInfo_SearchVehiclesRequest.Size(m)
+}
+func (m *SearchVehiclesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SearchVehiclesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SearchVehiclesRequest proto.InternalMessageInfo
+
+func (m *SearchVehiclesRequest) GetQuery() string {
+	if m != nil {
+		return m.Query
+	}
+	return ""
+}
+
+// VehicleResource is a stored vehicle including its server managed properties.
+type VehicleResource struct {
+	Vin                  string   `protobuf:"bytes,1,opt,name=vin,proto3" json:"vin,omitempty"`
+	Make                 string   `protobuf:"bytes,2,opt,name=make,proto3" json:"make,omitempty"`
+	Model                string   `protobuf:"bytes,3,opt,name=model,proto3" json:"model,omitempty"`
+	Year                 int32    `protobuf:"varint,4,opt,name=year,proto3" json:"year,omitempty"`
+	ExteriorColor        string   `protobuf:"bytes,5,opt,name=exterior_color,json=exteriorColor,proto3" json:"exterior_color,omitempty"`
+	InteriorColor        string   `protobuf:"bytes,6,opt,name=interior_color,json=interiorColor,proto3" json:"interior_color,omitempty"`
+	CreatedAt            int64    `protobuf:"varint,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt            int64    `protobuf:"varint,8,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	Etag                 string   `protobuf:"bytes,9,opt,name=etag,proto3" json:"etag,omitempty"`
+	Version              int64    `protobuf:"varint,10,opt,name=version,proto3" json:"version,omitempty"`
+	CreatedBy            string   `protobuf:"bytes,11,opt,name=created_by,json=createdBy,proto3" json:"created_by,omitempty"`
+	UpdatedBy            string   `protobuf:"bytes,12,opt,name=updated_by,json=updatedBy,proto3" json:"updated_by,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *VehicleResource) Reset()         { *m = VehicleResource{} }
+func (m *VehicleResource) String() string { return proto.CompactTextString(m) }
+func (*VehicleResource) ProtoMessage()    {}
+func (*VehicleResource) Descriptor() ([]byte, []int) {
+	return fileDescriptor_7867fac5d8e1caae, []int{7}
+}
+func (m *VehicleResource) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VehicleResource.Unmarshal(m, b)
+}
+func (m *VehicleResource) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VehicleResource.Marshal(b, m, deterministic)
+}
+func (m *VehicleResource) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VehicleResource.Merge(m, src)
+}
+func (m *VehicleResource) XXX_Size() int {
+	return xxx_messageInfo_VehicleResource.Size(m)
+}
+func (m *VehicleResource) XXX_DiscardUnknown() {
+	xxx_messageInfo_VehicleResource.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VehicleResource proto.InternalMessageInfo
+
+func (m *VehicleResource) GetVin() string {
+	if m != nil {
+		return m.Vin
+	}
+	return ""
+}
+
+func (m *VehicleResource) GetMake() string {
+	if m != nil {
+		return m.Make
+	}
+	return ""
+}
+
+func (m *VehicleResource) GetModel() string {
+	if m != nil {
+		return m.Model
+	}
+	return ""
+}
+
+func (m *VehicleResource) GetYear() int32 {
+	if m != nil {
+		return m.Year
+	}
+	return 0
+}
+
+func (m *VehicleResource) GetExteriorColor() string {
+	if m != nil {
+		return m.ExteriorColor
+	}
+	return ""
+}
+
+func (m *VehicleResource) GetInteriorColor() string {
+	if m != nil {
+		return m.InteriorColor
+	}
+	return ""
+}
+
+func (m *VehicleResource) GetCreatedAt() int64 {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return 0
+}
+
+func (m *VehicleResource) GetUpdatedAt() int64 {
+	if m != nil {
+		return m.UpdatedAt
+	}
+	return 0
+}
+
+func (m *VehicleResource) GetEtag() string {
+	if m != nil {
+		return m.Etag
+	}
+	return ""
+}
+
+func (m *VehicleResource) GetVersion() int64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *VehicleResource) GetCreatedBy() string {
+	if m != nil {
+		return m.CreatedBy
+	}
+	return ""
+}
+
+func (m *VehicleResource) GetUpdatedBy() string {
+	if m != nil {
+		return m.UpdatedBy
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*GetVehicleRequest)(nil), "vehicle.v2.GetVehicleRequest")
+	proto.RegisterType((*CreateVehicleRequest)(nil), "vehicle.v2.CreateVehicleRequest")
+	proto.RegisterType((*UpdateVehicleRequest)(nil), "vehicle.v2.UpdateVehicleRequest")
+	proto.RegisterType((*DeleteVehicleRequest)(nil), "vehicle.v2.DeleteVehicleRequest")
+	proto.RegisterType((*DeleteVehicleResponse)(nil), "vehicle.v2.DeleteVehicleResponse")
+	proto.RegisterType((*ListVehiclesRequest)(nil), "vehicle.v2.ListVehiclesRequest")
+	proto.RegisterType((*SearchVehiclesRequest)(nil), "vehicle.v2.SearchVehiclesRequest")
+	proto.RegisterType((*VehicleResource)(nil), "vehicle.v2.VehicleResource")
+}
+
+func init() { proto.RegisterFile("v2/vehicle.proto", fileDescriptor_7867fac5d8e1caae) }
+
+var fileDescriptor_7867fac5d8e1caae = []byte{
+	// 492 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xd4, 0x54, 0xcd, 0x6e, 0xd3, 0x40,
+	0x10, 0xc6, 0x49, 0xd3, 0x36, 0x43, 0x52, 0xca, 0x92, 0x88, 0x55, 0x50, 0x21, 0x58, 0xaa, 0xd4,
+	0x4b, 0x13, 0x14, 0xae, 0x5c, 0x9a, 0x22, 0x21, 0x21, 0x0e, 0x28, 0x85, 0x1e, 0xb8, 0x54, 0xb6,
+	0x33, 0x24, 0x2b, 0x12, 0x6f, 0xba, 0x5e, 0x5b, 0xf8, 0xbd, 0x78, 0x08, 0x5e, 0x81, 0x23, 0x6f,
+	0xc1, 0x09, 0xa1, 0xdd, 0xb5, 0xc9, 0x6e, 0xb0, 0x48, 0x8e, 0x70, 0x9b, 0x9f, 0xef, 0x9b, 0x19,
+	0x8f, 0xe7, 0x5b, 0x38, 0xce, 0x46, 0xc3, 0x0c, 0xe7, 0x2c, 0x5a, 0xe0, 0x60, 0x25, 0xb8, 0xe4,
+	0x04, 0x4a, 0x37, 0x1b, 0xf5, 0xce, 0x67, 0x4c, 0xce, 0xd3, 0x70, 0x10, 0xf1, 0xe5, 0x70, 0xc6,
+	0x67, 0x7c, 0xa8, 0x21, 0x61, 0xfa, 0x51, 0x7b, 0xda, 0xd1, 0x96, 0xa1, 0xfa, 0xa7, 0x70, 0xff,
+	0x15, 0xca, 0x6b, 0xc3, 0x9f, 0xe0, 0x6d, 0x8a, 0x89, 0x24, 0xc7, 0x50, 0xcf, 0x58, 0x4c, 0xbd,
+	0xbe, 0x77, 0xd6, 0x9c, 0x28, 0xd3, 0xff, 0xe2, 0x41, 0xe7, 0x52, 0x60, 0x20, 0x71, 0x1b, 0x94,
+	0x10, 0xd8, 0x5b, 0x06, 0x9f, 0x90, 0xd6, 0x74, 0x48, 0xdb, 0xa4, 0x03, 0x8d, 0x25, 0x9f, 0xe2,
+	0x82, 0xd6, 0x75, 0xd0, 0x38, 0x0a, 0x99, 0x63, 0x20, 0xe8, 0x5e, 0xdf, 0x3b, 0x6b, 0x4c, 0xb4,
+	0x4d, 0x4e, 0xe1, 0x08, 0x3f, 0x4b, 0x14, 0x8c, 0x8b, 0x9b, 0x88, 0x2f, 0xb8, 0xa0, 0x0d, 0x4d,
+	0x69, 0x97, 0xd1, 0x4b, 0x15, 0x54, 0x30, 0x16, 0x3b, 0xb0, 0x7d, 0x03, 0x63, 0xb1, 0x05, 0xf3,
+	0xbf, 0x7a, 0xd0, 0x79, 0xbf, 0x9a, 0xfe, 0x67, 0x63, 0xab, 0x0e, 0x28, 0x83, 0x19, 0x3d, 0x30,
+	0xb3, 0x28, 0xdb, 0x7f, 0x01, 0x9d, 0x97, 0xb8, 0xc0, 0xdd, 0xbe, 0x44, 0xb3, 0x6b, 0x16, 0xfb,
+	0x21, 0x74, 0x37, 0xd8, 0xc9, 0x8a, 0xc7, 0x09, 0xfa, 0x5d, 0x78, 0xf0, 0x86, 0x25, 0xe5, 0x01,
+	0x24, 0x45, 0x55, 0xff, 0x1c, 0xba, 0x57, 0x18, 0x88, 0x68, 0xbe, 0x91, 0x50, 0x2b, 0xb9, 0x4d,
+	0x51, 0xe4, 0x45, 0x43, 0xe3, 0xf8, 0xdf, 0x6a, 0x70, 0x6f, 0x5d, 0x99, 0xa7, 0x22, 0xc2, 0x7f,
+	0x7c, 0xc5, 0x27, 0x00, 0x91, 0xbe, 0xe7, 0xe9, 0x4d, 0x20, 0xf5, 0xa2, 0xeb, 0x93, 0x66, 0x11,
+	0xb9, 0x90, 0x2a, 0x9d, 0xae, 0xa6, 0x65, 0xfa, 0xd0, 0xa4, 0x8b, 0xc8, 0x85, 0xfc, 0xbd, 0xe2,
+	0xe6, 0x7a, 0xc5, 0x84, 0xc2, 0x41, 0x86, 0x22, 0x61, 0x3c, 0xa6, 0xa0, 0xf1, 0xa5, 0x6b, 0xf7,
+	0x0a, 0x73, 0x7a, 0x57, 0x73, 0xca, 0x5e, 0xe3, 0xdc, 0xee, 0x15, 0xe6, 0xb4, 0x65, 0xd2, 0x45,
+	0x64, 0x9c, 0x8f, 0x7e, 0xd6, 0xa1, 0x55, 0xec, 0xf6, 0x4a, 0x72, 0x81, 0xe4, 0x35, 0xc0, 0x5a,
+	0xb2, 0xe4, 0x64, 0xb0, 0x16, 0xff, 0xe0, 0x0f, 0x29, 0xf7, 0x1e, 0xd9, 0xe9, 0x8d, 0x5f, 0xe4,
+	0xdf, 0x21, 0x6f, 0xa1, 0xed, 0xc8, 0x9a, 0xf4, 0x6d, 0x7c, 0x95, 0xe2, 0x77, 0xa8, 0xe8, 0x28,
+	0xce, 0xad, 0x58, 0x25, 0xc6, 0x6d, 0x15, 0xaf, 0xa1, 0xed, 0xdc, 0xae, 0x5b, 0xb1, 0x4a, 0x14,
+	0xbd, 0xa7, 0x7f, 0x41, 0x14, 0x87, 0xaf, 0x26, 0x6d, 0xd9, 0xa7, 0x4f, 0x9e, 0xd8, 0xa4, 0x0a,
+	0x51, 0x6c, 0x99, 0xf3, 0x99, 0x47, 0xde, 0xc1, 0x91, 0xab, 0x1a, 0xe2, 0x0c, 0x52, 0xa9, 0xa8,
+	0xad, 0x55, 0xc7, 0x87, 0x3f, 0xbe, 0x3f, 0xf6, 0x3e, 0xd4, 0xb2, 0x51, 0xb8, 0xaf, 0xdf, 0xec,
+	0xe7, 0xbf, 0x06, 0x00, 0xf3, 0xb6, 0x49, 0x5b, 0x02, 0x06, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// VehicleStoreClient is the client API for VehicleStore service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type VehicleStoreClient interface {
+	GetVehicle(ctx context.Context, in *GetVehicleRequest, opts ...grpc.CallOption) (*VehicleResource, error)
+	CreateVehicle(ctx context.Context, in *CreateVehicleRequest, opts ...grpc.CallOption) (*VehicleResource, error)
+	UpdateVehicle(ctx context.Context, in *UpdateVehicleRequest, opts ...grpc.CallOption) (*VehicleResource, error)
+	DeleteVehicle(ctx context.Context, in *DeleteVehicleRequest, opts ...grpc.CallOption) (*DeleteVehicleResponse, error)
+	ListVehicles(ctx context.Context, in *ListVehiclesRequest, opts ...grpc.CallOption) (VehicleStore_ListVehiclesClient, error)
+	SearchVehicles(ctx context.Context, in *SearchVehiclesRequest, opts ...grpc.CallOption) (VehicleStore_SearchVehiclesClient, error)
+}
+
+type vehicleStoreClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewVehicleStoreClient(cc *grpc.ClientConn) VehicleStoreClient {
+	return &vehicleStoreClient{cc}
+}
+
+func (c *vehicleStoreClient) GetVehicle(ctx context.Context, in *GetVehicleRequest, opts ...grpc.CallOption) (*VehicleResource, error) {
+	out := new(VehicleResource)
+	err := c.cc.Invoke(ctx, "/vehicle.v2.VehicleStore/GetVehicle", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vehicleStoreClient) CreateVehicle(ctx context.Context, in *CreateVehicleRequest, opts ...grpc.CallOption) (*VehicleResource, error) {
+	out := new(VehicleResource)
+	err := c.cc.Invoke(ctx, "/vehicle.v2.VehicleStore/CreateVehicle", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vehicleStoreClient) UpdateVehicle(ctx context.Context, in *UpdateVehicleRequest, opts ...grpc.CallOption) (*VehicleResource, error) {
+	out := new(VehicleResource)
+	err := c.cc.Invoke(ctx, "/vehicle.v2.VehicleStore/UpdateVehicle", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vehicleStoreClient) DeleteVehicle(ctx context.Context, in *DeleteVehicleRequest, opts ...grpc.CallOption) (*DeleteVehicleResponse, error) {
+	out := new(DeleteVehicleResponse)
+	err := c.cc.Invoke(ctx, "/vehicle.v2.VehicleStore/DeleteVehicle", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vehicleStoreClient) ListVehicles(ctx context.Context, in *ListVehiclesRequest, opts ...grpc.CallOption) (VehicleStore_ListVehiclesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_VehicleStore_serviceDesc.Streams[0], "/vehicle.v2.VehicleStore/ListVehicles", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &vehicleStoreListVehiclesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type VehicleStore_ListVehiclesClient interface {
+	Recv() (*VehicleResource, error)
+	grpc.ClientStream
+}
+
+type vehicleStoreListVehiclesClient struct {
+	grpc.ClientStream
+}
+
+func (x *vehicleStoreListVehiclesClient) Recv() (*VehicleResource, error) {
+	m := new(VehicleResource)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *vehicleStoreClient) SearchVehicles(ctx context.Context, in *SearchVehiclesRequest, opts ...grpc.CallOption) (VehicleStore_SearchVehiclesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_VehicleStore_serviceDesc.Streams[1], "/vehicle.v2.VehicleStore/SearchVehicles", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &vehicleStoreSearchVehiclesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type VehicleStore_SearchVehiclesClient interface {
+	Recv() (*VehicleResource, error)
+	grpc.ClientStream
+}
+
+type vehicleStoreSearchVehiclesClient struct {
+	grpc.ClientStream
+}
+
+func (x *vehicleStoreSearchVehiclesClient) Recv() (*VehicleResource, error) {
+	m := new(VehicleResource)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// VehicleStoreServer is the server API for VehicleStore service.
+type VehicleStoreServer interface {
+	GetVehicle(context.Context, *GetVehicleRequest) (*VehicleResource, error)
+	CreateVehicle(context.Context, *CreateVehicleRequest) (*VehicleResource, error)
+	UpdateVehicle(context.Context, *UpdateVehicleRequest) (*VehicleResource, error)
+	DeleteVehicle(context.Context, *DeleteVehicleRequest) (*DeleteVehicleResponse, error)
+	ListVehicles(*ListVehiclesRequest, VehicleStore_ListVehiclesServer) error
+	SearchVehicles(*SearchVehiclesRequest, VehicleStore_SearchVehiclesServer) error
+}
+
+// UnimplementedVehicleStoreServer can be embedded to have forward compatible implementations.
+type UnimplementedVehicleStoreServer struct {
+}
+
+func (*UnimplementedVehicleStoreServer) GetVehicle(ctx context.Context, req *GetVehicleRequest) (*VehicleResource, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVehicle not implemented")
+}
+func (*UnimplementedVehicleStoreServer) CreateVehicle(ctx context.Context, req *CreateVehicleRequest) (*VehicleResource, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateVehicle not implemented")
+}
+func (*UnimplementedVehicleStoreServer) UpdateVehicle(ctx context.Context, req *UpdateVehicleRequest) (*VehicleResource, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateVehicle not implemented")
+}
+func (*UnimplementedVehicleStoreServer) DeleteVehicle(ctx context.Context, req *DeleteVehicleRequest) (*DeleteVehicleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteVehicle not implemented")
+}
+func (*UnimplementedVehicleStoreServer) ListVehicles(req *ListVehiclesRequest, srv VehicleStore_ListVehiclesServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListVehicles not implemented")
+}
+func (*UnimplementedVehicleStoreServer) SearchVehicles(req *SearchVehiclesRequest, srv VehicleStore_SearchVehiclesServer) error {
+	return status.Errorf(codes.Unimplemented, "method SearchVehicles not implemented")
+}
+
+func RegisterVehicleStoreServer(s *grpc.Server, srv VehicleStoreServer) {
+	s.RegisterService(&_VehicleStore_serviceDesc, srv)
+}
+
+func _VehicleStore_GetVehicle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetVehicleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VehicleStoreServer).GetVehicle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/vehicle.v2.VehicleStore/GetVehicle",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VehicleStoreServer).GetVehicle(ctx, req.(*GetVehicleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VehicleStore_CreateVehicle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateVehicleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VehicleStoreServer).CreateVehicle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/vehicle.v2.VehicleStore/CreateVehicle",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VehicleStoreServer).CreateVehicle(ctx, req.(*CreateVehicleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VehicleStore_UpdateVehicle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateVehicleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VehicleStoreServer).UpdateVehicle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/vehicle.v2.VehicleStore/UpdateVehicle",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VehicleStoreServer).UpdateVehicle(ctx, req.(*UpdateVehicleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VehicleStore_DeleteVehicle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteVehicleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VehicleStoreServer).DeleteVehicle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/vehicle.v2.VehicleStore/DeleteVehicle",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VehicleStoreServer).DeleteVehicle(ctx, req.(*DeleteVehicleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VehicleStore_ListVehicles_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListVehiclesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(VehicleStoreServer).ListVehicles(m, &vehicleStoreListVehiclesServer{stream})
+}
+
+type VehicleStore_ListVehiclesServer interface {
+	Send(*VehicleResource) error
+	grpc.ServerStream
+}
+
+type vehicleStoreListVehiclesServer struct {
+	grpc.ServerStream
+}
+
+func (x *vehicleStoreListVehiclesServer) Send(m *VehicleResource) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _VehicleStore_SearchVehicles_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SearchVehiclesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(VehicleStoreServer).SearchVehicles(m, &vehicleStoreSearchVehiclesServer{stream})
+}
+
+type VehicleStore_SearchVehiclesServer interface {
+	Send(*VehicleResource) error
+	grpc.ServerStream
+}
+
+type vehicleStoreSearchVehiclesServer struct {
+	grpc.ServerStream
+}
+
+func (x *vehicleStoreSearchVehiclesServer) Send(m *VehicleResource) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _VehicleStore_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "vehicle.v2.VehicleStore",
+	HandlerType: (*VehicleStoreServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetVehicle",
+			Handler:    _VehicleStore_GetVehicle_Handler,
+		},
+		{
+			MethodName: "CreateVehicle",
+			Handler:    _VehicleStore_CreateVehicle_Handler,
+		},
+		{
+			MethodName: "UpdateVehicle",
+			Handler:    _VehicleStore_UpdateVehicle_Handler,
+		},
+		{
+			MethodName: "DeleteVehicle",
+			Handler:    _VehicleStore_DeleteVehicle_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListVehicles",
+			Handler:       _VehicleStore_ListVehicles_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SearchVehicles",
+			Handler:       _VehicleStore_SearchVehicles_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "v2/vehicle.proto",
+}
+
+func NewPopulatedGetVehicleRequest(r randyVehicle, easy bool) *GetVehicleRequest {
+	this := &GetVehicleRequest{}
+	this.Vin = string(randStringVehicle(r))
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedVehicle(r, 2)
+	}
+	return this
+}
+
+func NewPopulatedCreateVehicleRequest(r randyVehicle, easy bool) *CreateVehicleRequest {
+	this := &CreateVehicleRequest{}
+	this.Vin = string(randStringVehicle(r))
+	this.Make = string(randStringVehicle(r))
+	this.Model = string(randStringVehicle(r))
+	this.Year = int32(r.Int31())
+	if r.Intn(2) == 0 {
+		this.Year *= -1
+	}
+	this.ExteriorColor = string(randStringVehicle(r))
+	this.InteriorColor = string(randStringVehicle(r))
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedVehicle(r, 7)
+	}
+	return this
+}
+
+func NewPopulatedUpdateVehicleRequest(r randyVehicle, easy bool) *UpdateVehicleRequest {
+	this := &UpdateVehicleRequest{}
+	this.Vin = string(randStringVehicle(r))
+	this.Make = string(randStringVehicle(r))
+	this.Model = string(randStringVehicle(r))
+	this.Year = int32(r.Int31())
+	if r.Intn(2) == 0 {
+		this.Year *= -1
+	}
+	this.ExteriorColor = string(randStringVehicle(r))
+	this.InteriorColor = string(randStringVehicle(r))
+	this.Etag = string(randStringVehicle(r))
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedVehicle(r, 8)
+	}
+	return this
+}
+
+func NewPopulatedDeleteVehicleRequest(r randyVehicle, easy bool) *DeleteVehicleRequest {
+	this := &DeleteVehicleRequest{}
+	this.Vin = string(randStringVehicle(r))
+	this.Etag = string(randStringVehicle(r))
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedVehicle(r, 3)
+	}
+	return this
+}
+
+func NewPopulatedDeleteVehicleResponse(r randyVehicle, easy bool) *DeleteVehicleResponse {
+	this := &DeleteVehicleResponse{}
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedVehicle(r, 1)
+	}
+	return this
+}
+
+func NewPopulatedListVehiclesRequest(r randyVehicle, easy bool) *ListVehiclesRequest {
+	this := &ListVehiclesRequest{}
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedVehicle(r, 1)
+	}
+	return this
+}
+
+func NewPopulatedSearchVehiclesRequest(r randyVehicle, easy bool) *SearchVehiclesRequest {
+	this := &SearchVehiclesRequest{}
+	this.Query = string(randStringVehicle(r))
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedVehicle(r, 2)
+	}
+	return this
+}
+
+func NewPopulatedVehicleResource(r randyVehicle, easy bool) *VehicleResource {
+	this := &VehicleResource{}
+	this.Vin = string(randStringVehicle(r))
+	this.Make = string(randStringVehicle(r))
+	this.Model = string(randStringVehicle(r))
+	this.Year = int32(r.Int31())
+	if r.Intn(2) == 0 {
+		this.Year *= -1
+	}
+	this.ExteriorColor = string(randStringVehicle(r))
+	this.InteriorColor = string(randStringVehicle(r))
+	this.CreatedAt = int64(r.Int63())
+	if r.Intn(2) == 0 {
+		this.CreatedAt *= -1
+	}
+	this.UpdatedAt = int64(r.Int63())
+	if r.Intn(2) == 0 {
+		this.UpdatedAt *= -1
+	}
+	this.Etag = string(randStringVehicle(r))
+	this.Version = int64(r.Int63())
+	if r.Intn(2) == 0 {
+		this.Version *= -1
+	}
+	this.CreatedBy = string(randStringVehicle(r))
+	this.UpdatedBy = string(randStringVehicle(r))
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedVehicle(r, 13)
+	}
+	return this
+}
+
+type randyVehicle interface {
+	Float32() float32
+	Float64() float64
+	Int63() int64
+	Int31() int32
+	Uint32() uint32
+	Intn(n int) int
+}
+
+func randUTF8RuneVehicle(r randyVehicle) rune {
+	ru := r.Intn(62)
+	if ru < 10 {
+		return rune(ru + 48)
+	} else if ru < 36 {
+		return rune(ru + 55)
+	}
+	return rune(ru + 61)
+}
+func randStringVehicle(r randyVehicle) string {
+	v1 := r.Intn(100)
+	tmps := make([]rune, v1)
+	for i := 0; i < v1; i++ {
+		tmps[i] = randUTF8RuneVehicle(r)
+	}
+	return string(tmps)
+}
+func randUnrecognizedVehicle(r randyVehicle, maxFieldNumber int) (dAtA []byte) {
+	l := r.Intn(5)
+	for i := 0; i < l; i++ {
+		wire := r.Intn(4)
+		if wire == 3 {
+			wire = 5
+		}
+		fieldNumber := maxFieldNumber + r.Intn(100)
+		dAtA = randFieldVehicle(dAtA, r, fieldNumber, wire)
+	}
+	return dAtA
+}
+func randFieldVehicle(dAtA []byte, r randyVehicle, fieldNumber int, wire int) []byte {
+	key := uint32(fieldNumber)<<3 | uint32(wire)
+	switch wire {
+	case 0:
+		dAtA = encodeVarintPopulateVehicle(dAtA, uint64(key))
+		v2 := r.Int63()
+		if r.Intn(2) == 0 {
+			v2 *= -1
+		}
+		dAtA = encodeVarintPopulateVehicle(dAtA, uint64(v2))
+	case 1:
+		dAtA = encodeVarintPopulateVehicle(dAtA, uint64(key))
+		dAtA = append(dAtA, byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)))
+	case 2:
+		dAtA = encodeVarintPopulateVehicle(dAtA, uint64(key))
+		ll := r.Intn(100)
+		dAtA = encodeVarintPopulateVehicle(dAtA, uint64(ll))
+		for j := 0; j < ll; j++ {
+			dAtA = append(dAtA, byte(r.Intn(256)))
+		}
+	default:
+		dAtA = encodeVarintPopulateVehicle(dAtA, uint64(key))
+		dAtA = append(dAtA, byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)))
+	}
+	return dAtA
+}
+func encodeVarintPopulateVehicle(dAtA []byte, v uint64) []byte {
+	for v >= 1<<7 {
+		dAtA = append(dAtA, uint8(uint64(v)&0x7f|0x80))
+		v >>= 7
+	}
+	dAtA = append(dAtA, uint8(v))
+	return dAtA
+}
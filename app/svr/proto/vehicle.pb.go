@@ -63,7 +63,9 @@ func (m *VehicleVIN) GetVin() string {
 	return ""
 }
 
-// TODO: consider creating a separate request/reponse vehicle since updated_at can't be set on request
+// NB: Vehicle is used for both requests and responses; the server managed created_at, created_by,
+// updated_at, updated_by and version are ignored on requests. See
+// vehicle.v2 in v2/vehicle.proto for separate request and response messages.
 type Vehicle struct {
 	Vin                  string   `protobuf:"bytes,1,opt,name=vin,proto3" json:"vin,omitempty" xml:"vin"`
 	Make                 string   `protobuf:"bytes,2,opt,name=make,proto3" json:"make,omitempty" xml:"make"`
@@ -72,6 +74,10 @@ type Vehicle struct {
 	ExteriorColor        string   `protobuf:"bytes,5,opt,name=exterior_color,json=exteriorColor,proto3" json:"exterior_color,omitempty" db:"exterior_color" xml:"exterior_color"`
 	InteriorColor        string   `protobuf:"bytes,6,opt,name=interior_color,json=interiorColor,proto3" json:"interior_color,omitempty" db:"interior_color" xml:"interior_color"`
 	UpdatedAt            int64    `protobuf:"varint,7,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty" db:"updated_at" xml:"updated_at"`
+	CreatedAt            int64    `protobuf:"varint,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty" db:"created_at" xml:"created_at"`
+	CreatedBy            string   `protobuf:"bytes,9,opt,name=created_by,json=createdBy,proto3" json:"created_by,omitempty" db:"created_by" xml:"created_by"`
+	UpdatedBy            string   `protobuf:"bytes,10,opt,name=updated_by,json=updatedBy,proto3" json:"updated_by,omitempty" db:"updated_by" xml:"updated_by"`
+	Version              int64    `protobuf:"varint,11,opt,name=version,proto3" json:"version,omitempty" db:"version" xml:"version"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -150,6 +156,73 @@ func (m *Vehicle) GetUpdatedAt() int64 {
 	return 0
 }
 
+func (m *Vehicle) GetCreatedAt() int64 {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return 0
+}
+
+func (m *Vehicle) GetCreatedBy() string {
+	if m != nil {
+		return m.CreatedBy
+	}
+	return ""
+}
+
+func (m *Vehicle) GetUpdatedBy() string {
+	if m != nil {
+		return m.UpdatedBy
+	}
+	return ""
+}
+
+func (m *Vehicle) GetVersion() int64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+// VehicleList wraps a collection of vehicles for encodings that require a single message.
+type VehicleList struct {
+	Vehicles             []*Vehicle `protobuf:"bytes,1,rep,name=vehicles,proto3" json:"vehicles,omitempty" xml:"vehicle"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *VehicleList) Reset()         { *m = VehicleList{} }
+func (m *VehicleList) String() string { return proto.CompactTextString(m) }
+func (*VehicleList) ProtoMessage()    {}
+func (*VehicleList) Descriptor() ([]byte, []int) {
+	return fileDescriptor_416ab71f8212867c, []int{2}
+}
+func (m *VehicleList) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VehicleList.Unmarshal(m, b)
+}
+func (m *VehicleList) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VehicleList.Marshal(b, m, deterministic)
+}
+func (m *VehicleList) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VehicleList.Merge(m, src)
+}
+func (m *VehicleList) XXX_Size() int {
+	return xxx_messageInfo_VehicleList.Size(m)
+}
+func (m *VehicleList) XXX_DiscardUnknown() {
+	xxx_messageInfo_VehicleList.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VehicleList proto.InternalMessageInfo
+
+func (m *VehicleList) GetVehicles() []*Vehicle {
+	if m != nil {
+		return m.Vehicles
+	}
+	return nil
+}
+
 type VehicleQuery struct {
 	Query                string   `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -161,7 +234,7 @@ func (m *VehicleQuery) Reset()         { *m = VehicleQuery{} }
 func (m *VehicleQuery) String() string { return proto.CompactTextString(m) }
 func (*VehicleQuery) ProtoMessage()    {}
 func (*VehicleQuery) Descriptor() ([]byte, []int) {
-	return fileDescriptor_416ab71f8212867c, []int{2}
+	return fileDescriptor_416ab71f8212867c, []int{3}
 }
 func (m *VehicleQuery) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_VehicleQuery.Unmarshal(m, b)
@@ -198,7 +271,7 @@ func (m *EmptyMessage) Reset()         { *m = EmptyMessage{} }
 func (m *EmptyMessage) String() string { return proto.CompactTextString(m) }
 func (*EmptyMessage) ProtoMessage()    {}
 func (*EmptyMessage) Descriptor() ([]byte, []int) {
-	return fileDescriptor_416ab71f8212867c, []int{3}
+	return fileDescriptor_416ab71f8212867c, []int{4}
 }
 func (m *EmptyMessage) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_EmptyMessage.Unmarshal(m, b)
@@ -221,6 +294,7 @@ var xxx_messageInfo_EmptyMessage proto.InternalMessageInfo
 func init() {
 	proto.RegisterType((*VehicleVIN)(nil), "vehicle.VehicleVIN")
 	proto.RegisterType((*Vehicle)(nil), "vehicle.Vehicle")
+	proto.RegisterType((*VehicleList)(nil), "vehicle.VehicleList")
 	proto.RegisterType((*VehicleQuery)(nil), "vehicle.VehicleQuery")
 	proto.RegisterType((*EmptyMessage)(nil), "vehicle.EmptyMessage")
 }
@@ -228,35 +302,42 @@ func init() {
 func init() { proto.RegisterFile("vehicle.proto", fileDescriptor_416ab71f8212867c) }
 
 var fileDescriptor_416ab71f8212867c = []byte{
-	// 444 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x53, 0x4f, 0x6f, 0x94, 0x40,
-	0x14, 0x2f, 0xdd, 0xd2, 0xcd, 0xbe, 0x2e, 0xd8, 0x8c, 0x9a, 0x90, 0x1e, 0x0a, 0x99, 0xd4, 0x84,
-	0x83, 0x6e, 0x8d, 0xa6, 0x97, 0x9a, 0x1e, 0xdc, 0xd5, 0x18, 0x13, 0x35, 0x91, 0x8d, 0x3d, 0x78,
-	0x69, 0x58, 0x78, 0xb2, 0x44, 0xe8, 0xac, 0xc3, 0xd0, 0x74, 0xbf, 0x81, 0x9f, 0x92, 0xc4, 0x2f,
-	0xe0, 0x81, 0x93, 0x47, 0x33, 0x03, 0x74, 0x61, 0xc5, 0x43, 0x4f, 0xcc, 0xef, 0x2f, 0xbc, 0xbc,
-	0x01, 0x8c, 0x1b, 0x5c, 0xc6, 0x41, 0x82, 0x93, 0x15, 0x67, 0x82, 0x91, 0x61, 0x0d, 0x8f, 0x9e,
-	0x45, 0xb1, 0x58, 0xe6, 0x8b, 0x49, 0xc0, 0xd2, 0xd3, 0x88, 0x45, 0xec, 0x54, 0xe9, 0x8b, 0xfc,
-	0x9b, 0x42, 0x0a, 0xa8, 0x53, 0x95, 0xa3, 0xc7, 0x00, 0x97, 0x55, 0xf2, 0xf2, 0xfd, 0x27, 0x72,
-	0x08, 0x83, 0x9b, 0xf8, 0xda, 0xd2, 0x1c, 0xcd, 0x1d, 0x79, 0xf2, 0x48, 0x7f, 0x0e, 0x60, 0x58,
-	0x1b, 0x88, 0xdd, 0x52, 0xa7, 0x46, 0x59, 0xd8, 0xa3, 0xdb, 0x34, 0x39, 0xa7, 0xd2, 0xa6, 0xcc,
-	0x84, 0xc2, 0x5e, 0xea, 0x7f, 0x47, 0x6b, 0x57, 0x39, 0xcc, 0xb2, 0xb0, 0x41, 0x39, 0x24, 0x49,
-	0x3d, 0xa5, 0x91, 0x27, 0xa0, 0xa7, 0x2c, 0xc4, 0xc4, 0x1a, 0x28, 0xd3, 0x83, 0xb2, 0xb0, 0x0f,
-	0x2a, 0x93, 0x64, 0xa9, 0x57, 0xa9, 0xb2, 0x6a, 0x8d, 0x3e, 0xb7, 0xf6, 0x1c, 0xcd, 0xd5, 0x5b,
-	0x55, 0x92, 0xa4, 0x9e, 0xd2, 0xc8, 0x1c, 0x4c, 0xbc, 0x15, 0xc8, 0x63, 0xc6, 0xaf, 0x02, 0x96,
-	0x30, 0x6e, 0xe9, 0xaa, 0xf3, 0x69, 0x59, 0xd8, 0x6e, 0xb8, 0x38, 0xa7, 0x5d, 0x95, 0x3a, 0xaa,
-	0x61, 0x8b, 0xf4, 0x8c, 0x86, 0x98, 0x49, 0x2c, 0x4b, 0xe3, 0xeb, 0x4e, 0xe9, 0x7e, 0xb7, 0xb4,
-	0xab, 0xd6, 0xa5, 0x5b, 0xa4, 0x67, 0x34, 0x44, 0x55, 0x3a, 0x03, 0xc8, 0x57, 0xa1, 0x2f, 0x30,
-	0xbc, 0xf2, 0x85, 0x35, 0x74, 0x34, 0x77, 0x30, 0x3d, 0x29, 0x0b, 0xdb, 0x91, 0x85, 0x1b, 0xa5,
-	0x2e, 0x6b, 0x11, 0xde, 0xa8, 0x06, 0xaf, 0x05, 0x3d, 0x81, 0x71, 0xbd, 0x89, 0xcf, 0x39, 0xf2,
-	0x35, 0x79, 0x04, 0xfa, 0x0f, 0x79, 0xa8, 0xd7, 0x55, 0x01, 0x6a, 0xc2, 0xf8, 0x6d, 0xba, 0x12,
-	0xeb, 0x8f, 0x98, 0x65, 0x7e, 0x84, 0x2f, 0x7e, 0xef, 0xde, 0xc5, 0xe6, 0x82, 0x71, 0x24, 0x67,
-	0x00, 0xef, 0x50, 0x34, 0x3b, 0x7d, 0x38, 0x69, 0xee, 0xd1, 0xe6, 0x1a, 0x1c, 0x1d, 0x6e, 0x93,
-	0x74, 0x87, 0x9c, 0x81, 0x31, 0xe3, 0xe8, 0x0b, 0x6c, 0x92, 0xff, 0x98, 0xfe, 0x17, 0xfb, 0xa2,
-	0x26, 0xb8, 0x5f, 0xec, 0x02, 0x8c, 0x37, 0x98, 0xe0, 0x26, 0xd6, 0xfb, 0x9d, 0x8f, 0xef, 0xc8,
-	0xf6, 0xc8, 0x74, 0x87, 0xbc, 0x82, 0xf1, 0x87, 0x38, 0x6b, 0x86, 0xcc, 0x48, 0xbf, 0xb1, 0xef,
-	0xcd, 0xcf, 0x35, 0x72, 0x01, 0xe6, 0x1c, 0x7d, 0x1e, 0x2c, 0x7b, 0xe2, 0xed, 0x05, 0xf4, 0xc7,
-	0xa7, 0x07, 0x7f, 0x7e, 0x1d, 0x6b, 0x5f, 0xf5, 0xea, 0xb7, 0xdb, 0x57, 0x8f, 0x97, 0x7f, 0x03,
-	0x00, 0x00, 0xff, 0xff, 0x3e, 0xde, 0xdb, 0x81, 0xae, 0x03, 0x00, 0x00,
+	// 555 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x94, 0x41, 0x8f, 0xd2, 0x40,
+	0x14, 0xc7, 0xb7, 0xcb, 0x76, 0x59, 0x1e, 0x14, 0xd7, 0x51, 0x93, 0x86, 0x03, 0x6d, 0x26, 0x6b,
+	0xc2, 0x41, 0x59, 0xb3, 0x66, 0x13, 0xb3, 0x66, 0x63, 0x2c, 0x1a, 0xb3, 0x89, 0x92, 0x58, 0x22,
+	0x07, 0x2f, 0x9b, 0x16, 0x46, 0x68, 0x04, 0x06, 0xa7, 0x03, 0xd9, 0x7e, 0x0c, 0xbf, 0x60, 0x13,
+	0xbf, 0x80, 0x87, 0x9e, 0x3c, 0x9a, 0x99, 0x4e, 0xa1, 0x85, 0x6a, 0xb2, 0x27, 0xe6, 0xfd, 0xdf,
+	0xff, 0xff, 0x63, 0xde, 0x30, 0x03, 0x18, 0x6b, 0x32, 0x0d, 0x46, 0x33, 0xd2, 0x5d, 0x32, 0xca,
+	0x29, 0xaa, 0xaa, 0xb2, 0xf5, 0x7c, 0x12, 0xf0, 0xe9, 0xca, 0xef, 0x8e, 0xe8, 0xfc, 0x7c, 0x42,
+	0x27, 0xf4, 0x5c, 0xf6, 0xfd, 0xd5, 0x37, 0x59, 0xc9, 0x42, 0xae, 0xd2, 0x1c, 0x6e, 0x03, 0x0c,
+	0xd3, 0xe4, 0xf0, 0xa6, 0x8f, 0x4e, 0xa1, 0xb2, 0x0e, 0x16, 0xa6, 0x66, 0x6b, 0x9d, 0x9a, 0x2b,
+	0x96, 0xf8, 0xa7, 0x0e, 0x55, 0x65, 0x40, 0x56, 0xae, 0xeb, 0x18, 0x49, 0x6c, 0xd5, 0xee, 0xe6,
+	0xb3, 0x2b, 0x2c, 0x6c, 0xd2, 0x8c, 0x30, 0x1c, 0xcd, 0xbd, 0xef, 0xc4, 0x3c, 0x94, 0x8e, 0x66,
+	0x12, 0x5b, 0x20, 0x1d, 0x42, 0xc4, 0xae, 0xec, 0xa1, 0xa7, 0xa0, 0xcf, 0xe9, 0x98, 0xcc, 0xcc,
+	0x8a, 0x34, 0x3d, 0x48, 0x62, 0xab, 0x9e, 0x9a, 0x84, 0x8a, 0xdd, 0xb4, 0x2b, 0x50, 0x11, 0xf1,
+	0x98, 0x79, 0x64, 0x6b, 0x1d, 0x3d, 0x87, 0x12, 0x22, 0x76, 0x65, 0x0f, 0x0d, 0xa0, 0x49, 0xee,
+	0x38, 0x61, 0x01, 0x65, 0xb7, 0x23, 0x3a, 0xa3, 0xcc, 0xd4, 0x25, 0xf3, 0x59, 0x12, 0x5b, 0x9d,
+	0xb1, 0x7f, 0x85, 0x8b, 0x5d, 0x6c, 0x4b, 0xc2, 0x8e, 0xe8, 0x1a, 0x99, 0xd0, 0x13, 0xb5, 0x80,
+	0x06, 0x8b, 0x02, 0xf4, 0xb8, 0x08, 0x0d, 0x16, 0x25, 0xd0, 0x1d, 0xd1, 0x35, 0x82, 0x45, 0x1e,
+	0xda, 0x03, 0x58, 0x2d, 0xc7, 0x1e, 0x27, 0xe3, 0x5b, 0x8f, 0x9b, 0x55, 0x5b, 0xeb, 0x54, 0x9c,
+	0xb3, 0x24, 0xb6, 0x6c, 0x01, 0xdc, 0x76, 0x14, 0x2c, 0x27, 0xb8, 0x35, 0x55, 0xbc, 0xe5, 0x02,
+	0x32, 0x62, 0x24, 0x83, 0x9c, 0x14, 0x21, 0xdb, 0x8e, 0x82, 0xe4, 0x04, 0xb7, 0xa6, 0x8a, 0x22,
+	0xc4, 0x8f, 0xcc, 0x9a, 0x1c, 0x6d, 0x0f, 0xe2, 0x47, 0x3b, 0x10, 0x3f, 0xda, 0x42, 0x9c, 0x28,
+	0x3f, 0x8e, 0x1f, 0x99, 0x50, 0x84, 0x6c, 0x3b, 0x3b, 0xe3, 0x48, 0x88, 0x2a, 0x9c, 0x08, 0xbd,
+	0x82, 0xea, 0x9a, 0xb0, 0x30, 0xa0, 0x0b, 0xb3, 0x2e, 0x67, 0x69, 0x27, 0xb1, 0xd5, 0x12, 0x04,
+	0x25, 0xab, 0x78, 0x56, 0xb9, 0x99, 0x1d, 0xf7, 0xa1, 0xae, 0xae, 0xe4, 0xc7, 0x20, 0xe4, 0xe8,
+	0x0d, 0x9c, 0xa8, 0xcb, 0x1f, 0x9a, 0x9a, 0x5d, 0xe9, 0xd4, 0x2f, 0x4e, 0xbb, 0xd9, 0xe3, 0x50,
+	0x3e, 0xe7, 0x61, 0x12, 0x5b, 0x86, 0xc2, 0x49, 0x05, 0xbb, 0x9b, 0x10, 0x3e, 0x83, 0x86, 0xf2,
+	0x7d, 0x5e, 0x11, 0x16, 0xa1, 0xc7, 0xa0, 0xff, 0x10, 0x0b, 0xf5, 0x0e, 0xd2, 0x02, 0x37, 0xa1,
+	0xf1, 0x7e, 0xbe, 0xe4, 0xd1, 0x27, 0x12, 0x86, 0xde, 0x84, 0x5c, 0xfc, 0x3e, 0xdc, 0xc4, 0x06,
+	0x9c, 0x32, 0x82, 0x2e, 0x01, 0x3e, 0x10, 0x9e, 0x3d, 0x96, 0x47, 0xbb, 0x7b, 0x18, 0xde, 0xf4,
+	0x5b, 0x7b, 0x1b, 0xc3, 0x07, 0xe8, 0x12, 0x8c, 0x9e, 0x3c, 0xd9, 0x2c, 0xb9, 0x67, 0xfa, 0x57,
+	0xec, 0x8b, 0x3c, 0xcb, 0xfb, 0xc5, 0xae, 0xc1, 0x78, 0x47, 0x66, 0x84, 0x93, 0xff, 0xee, 0xf3,
+	0xc9, 0x46, 0xcc, 0x8f, 0x8c, 0x0f, 0xd0, 0x6b, 0x68, 0x88, 0x33, 0x57, 0xd6, 0x10, 0x95, 0x1b,
+	0xcb, 0xbe, 0xf9, 0x85, 0x86, 0xae, 0xa1, 0x39, 0x20, 0x1e, 0x1b, 0x4d, 0x4b, 0xe2, 0xf9, 0x1f,
+	0xa0, 0x3c, 0xee, 0xd4, 0xff, 0xfc, 0x6a, 0x6b, 0x5f, 0xf5, 0xf4, 0xff, 0xec, 0x58, 0x7e, 0xbc,
+	0xfc, 0x3b, 0x00, 0xb7, 0x14, 0xe6, 0xe5, 0x07, 0x05, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -598,8 +679,33 @@ func NewPopulatedVehicle(r randyVehicle, easy bool) *Vehicle {
 	if r.Intn(2) == 0 {
 		this.UpdatedAt *= -1
 	}
+	this.CreatedAt = int64(r.Int63())
+	if r.Intn(2) == 0 {
+		this.CreatedAt *= -1
+	}
+	this.CreatedBy = string(randStringVehicle(r))
+	this.UpdatedBy = string(randStringVehicle(r))
+	this.Version = int64(r.Int63())
+	if r.Intn(2) == 0 {
+		this.Version *= -1
+	}
+	if !easy && r.Intn(10) != 0 {
+		this.XXX_unrecognized = randUnrecognizedVehicle(r, 12)
+	}
+	return this
+}
+
+func NewPopulatedVehicleList(r randyVehicle, easy bool) *VehicleList {
+	this := &VehicleList{}
+	if r.Intn(5) != 0 {
+		v1 := r.Intn(5)
+		this.Vehicles = make([]*Vehicle, v1)
+		for i := 0; i < v1; i++ {
+			this.Vehicles[i] = NewPopulatedVehicle(r, easy)
+		}
+	}
 	if !easy && r.Intn(10) != 0 {
-		this.XXX_unrecognized = randUnrecognizedVehicle(r, 8)
+		this.XXX_unrecognized = randUnrecognizedVehicle(r, 2)
 	}
 	return this
 }
@@ -640,9 +746,9 @@ func randUTF8RuneVehicle(r randyVehicle) rune {
 	return rune(ru + 61)
 }
 func randStringVehicle(r randyVehicle) string {
-	v1 := r.Intn(100)
-	tmps := make([]rune, v1)
-	for i := 0; i < v1; i++ {
+	v2 := r.Intn(100)
+	tmps := make([]rune, v2)
+	for i := 0; i < v2; i++ {
 		tmps[i] = randUTF8RuneVehicle(r)
 	}
 	return string(tmps)
@@ -664,11 +770,11 @@ func randFieldVehicle(dAtA []byte, r randyVehicle, fieldNumber int, wire int) []
 	switch wire {
 	case 0:
 		dAtA = encodeVarintPopulateVehicle(dAtA, uint64(key))
-		v2 := r.Int63()
+		v3 := r.Int63()
 		if r.Intn(2) == 0 {
-			v2 *= -1
+			v3 *= -1
 		}
-		dAtA = encodeVarintPopulateVehicle(dAtA, uint64(v2))
+		dAtA = encodeVarintPopulateVehicle(dAtA, uint64(v3))
 	case 1:
 		dAtA = encodeVarintPopulateVehicle(dAtA, uint64(key))
 		dAtA = append(dAtA, byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)), byte(r.Intn(256)))
@@ -0,0 +1,51 @@
+package proto
+
+import (
+	"encoding/json"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// NB: the VehicleList is encoded as a bare array of vehicles by every encoding that can represent
+// one, i.e. all but XML and protobuf, so lists have the same shape as JSON, which YAML is derived from.
+
+// MarshalJSON marshals the VehicleList as a bare JSON array to remain compatible with
+// existing REST API clients.
+func (m *VehicleList) MarshalJSON() ([]byte, error) {
+	if m.Vehicles == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(m.Vehicles)
+}
+
+// UnmarshalJSON unmarshals a bare JSON array of vehicles into the VehicleList.
+func (m *VehicleList) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &m.Vehicles)
+}
+
+// EncodeMsgpack encodes the VehicleList as a bare MessagePack array.
+func (m *VehicleList) EncodeMsgpack(encoder *msgpack.Encoder) error {
+	if m.Vehicles == nil {
+		return encoder.Encode([]*Vehicle{})
+	}
+	return encoder.Encode(m.Vehicles)
+}
+
+// DecodeMsgpack decodes a bare MessagePack array of vehicles into the VehicleList.
+func (m *VehicleList) DecodeMsgpack(decoder *msgpack.Decoder) error {
+	return decoder.Decode(&m.Vehicles)
+}
+
+// MarshalCBOR marshals the VehicleList as a bare CBOR array.
+func (m *VehicleList) MarshalCBOR() ([]byte, error) {
+	if m.Vehicles == nil {
+		return cbor.Marshal([]*Vehicle{})
+	}
+	return cbor.Marshal(m.Vehicles)
+}
+
+// UnmarshalCBOR unmarshals a bare CBOR array of vehicles into the VehicleList.
+func (m *VehicleList) UnmarshalCBOR(data []byte) error {
+	return cbor.Unmarshal(data, &m.Vehicles)
+}
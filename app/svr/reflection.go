@@ -0,0 +1,119 @@
+package svr
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/bodenr/vehicle-api/log"
+	gogoproto "github.com/gogo/protobuf/proto"
+	protov2 "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/runtime/protoimpl"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// gogoProtoPath is the path the API protos import gogo.proto from, which gogo registers as gogo.proto.
+const gogoProtoPath = "github.com/gogo/protobuf/gogoproto/gogo.proto"
+
+// apiProtoFiles are the API proto files as registered with gogo protobuf.
+var apiProtoFiles = []string{"vehicle.proto", "err.proto", "v2/vehicle.proto", "admin/admin.proto"}
+
+var (
+	descriptorsOnce sync.Once
+	descriptorsErr  error
+	descriptorSet   []byte
+)
+
+// gogoFileDescriptor returns the FileDescriptorProto registered with gogo protobuf under the said name.
+func gogoFileDescriptor(name string) (*descriptorpb.FileDescriptorProto, error) {
+	enc := gogoproto.FileDescriptor(name)
+	if enc == nil {
+		return nil, fmt.Errorf("No file descriptor registered for %s", name)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(enc))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decompress file descriptor %s: %v", name, err)
+	}
+	raw, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decompress file descriptor %s: %v", name, err)
+	}
+	file := &descriptorpb.FileDescriptorProto{}
+	if err = protov2.Unmarshal(raw, file); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal file descriptor %s: %v", name, err)
+	}
+	return file, nil
+}
+
+// registerDescriptors registers the API proto files, which gogo protobuf keeps in its own registry,
+// with the protobuf registry used by gRPC reflection and builds the FileDescriptorSet of the APIs.
+// It's safe to call more than once.
+func registerDescriptors() error {
+	descriptorsOnce.Do(func() {
+		descriptorsErr = buildDescriptors()
+	})
+	return descriptorsErr
+}
+
+// buildDescriptors does the work of registerDescriptors.
+func buildDescriptors() error {
+	gogo, err := gogoFileDescriptor("gogo.proto")
+	if err != nil {
+		return err
+	}
+	gogo.Name = protov2.String(gogoProtoPath)
+
+	// NB: files are ordered so that dependencies come first
+	set := &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{
+			protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto),
+			gogo,
+		},
+	}
+	for _, name := range apiProtoFiles {
+		file, err := gogoFileDescriptor(name)
+		if err != nil {
+			return err
+		}
+		set.File = append(set.File, file)
+	}
+
+	for _, file := range set.File {
+		if _, err := protoregistry.GlobalFiles.FindFileByPath(file.GetName()); err == nil {
+			continue
+		}
+		// NB: validate the file first as the builder panics on invalid descriptors
+		if _, err := protodesc.NewFile(file, protoregistry.GlobalFiles); err != nil {
+			return fmt.Errorf("Invalid file descriptor %s: %v", file.GetName(), err)
+		}
+		raw, err := protov2.Marshal(file)
+		if err != nil {
+			return fmt.Errorf("Failed to marshal file descriptor %s: %v", file.GetName(), err)
+		}
+		// NB: gRPC reflection needs the raw descriptor of files, which is only kept when they're
+		// registered by the same builder used by generated code
+		protoimpl.DescBuilder{RawDescriptor: raw}.Build()
+	}
+
+	descriptorSet, err = protov2.Marshal(set)
+	return err
+}
+
+// DescriptorSetHandler serves the FileDescriptorSet of the APIs and their dependencies in the binary
+// protobuf format, e.g. for use with grpcurl -protoset.
+func DescriptorSetHandler(writer http.ResponseWriter, request *http.Request) {
+	if err := registerDescriptors(); err != nil {
+		log.FromContext(request.Context()).Err(err).Msg("Failed to build descriptor set")
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", ContentAppProtobuf)
+	writer.Header().Set("Content-Disposition", `attachment; filename="vehicle-api.protoset"`)
+	writer.WriteHeader(http.StatusOK)
+	writer.Write(descriptorSet)
+}
@@ -0,0 +1,187 @@
+package svr
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// StreamEncoder is optionally registered for encodings that write data onto a writer rather than
+// marshalling it; responses are still encoded into a buffer before being written so an encoding
+// error results in an error response rather than a truncated one.
+type StreamEncoder interface {
+	// Encode the data object onto the writer.
+	Encode(writer io.Writer, dataType interface{}) error
+}
+
+// EncodingOption configures an encoding when it's registered.
+type EncodingOption func(*registeredEncoding)
+
+// registeredEncoding is an Encoding along with its registration options.
+type registeredEncoding struct {
+	mediaType string
+	encoding  Encoding
+	aliases   []string
+	priority  int
+	stream    StreamEncoder
+	order     int
+	errorOnly bool
+}
+
+// encodingRegistry holds the registered encodings keyed by media type and alias.
+type encodingRegistry struct {
+	lock      sync.RWMutex
+	byType    map[string]*registeredEncoding
+	preferred []string
+	count     int
+}
+
+var registry = &encodingRegistry{
+	byType: map[string]*registeredEncoding{},
+}
+
+// WithAliases registers the encoding under additional media types, e.g. text/xml for application/xml.
+func WithAliases(aliases ...string) EncodingOption {
+	return func(reg *registeredEncoding) {
+		reg.aliases = append(reg.aliases, aliases...)
+	}
+}
+
+// WithPriority sets the server preference of the encoding used to break ties when negotiating the
+// response content type; higher values are preferred and the default is 0.
+func WithPriority(priority int) EncodingOption {
+	return func(reg *registeredEncoding) {
+		reg.priority = priority
+	}
+}
+
+// WithStreamEncoder sets a StreamEncoder used to write responses for the encoding in place of the
+// StoredResource Marshal; encodings are only streamed when registered with this option.
+func WithStreamEncoder(stream StreamEncoder) EncodingOption {
+	return func(reg *registeredEncoding) {
+		reg.stream = stream
+	}
+}
+
+// WithErrorsOnly registers the encoding for error responses only, e.g. application/problem+json; it's
+// neither negotiated from the Accept header nor accepted as request content.
+func WithErrorsOnly() EncodingOption {
+	return func(reg *registeredEncoding) {
+		reg.errorOnly = true
+	}
+}
+
+// RegisterEncoding registers the encoding for the said media type, replacing any existing
+// registration of the media type or its aliases.
+func RegisterEncoding(mediaType string, enc Encoding, opts ...EncodingOption) error {
+	parsedType, _, err := ParseMediaType(mediaType)
+	if err != nil {
+		return fmt.Errorf("Invalid media type %s: %v", mediaType, err)
+	}
+	if enc == nil {
+		return fmt.Errorf("No encoding given for %s", mediaType)
+	}
+	reg := &registeredEncoding{
+		mediaType: parsedType,
+		encoding:  enc,
+	}
+	for _, opt := range opts {
+		opt(reg)
+	}
+	for i, alias := range reg.aliases {
+		if reg.aliases[i], _, err = ParseMediaType(alias); err != nil {
+			return fmt.Errorf("Invalid media type alias %s: %v", alias, err)
+		}
+	}
+
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	// drop the aliases of any previous registration of the media type
+	for registered, existing := range registry.byType {
+		if existing.mediaType == reg.mediaType {
+			delete(registry.byType, registered)
+		}
+	}
+	registry.count++
+	reg.order = registry.count
+	for _, mediaType := range append([]string{reg.mediaType}, reg.aliases...) {
+		registry.byType[mediaType] = reg
+	}
+	registry.sortPreferred()
+	return nil
+}
+
+// MustRegisterEncoding registers the encoding as per RegisterEncoding and panics on error.
+func MustRegisterEncoding(mediaType string, enc Encoding, opts ...EncodingOption) {
+	if err := RegisterEncoding(mediaType, enc, opts...); err != nil {
+		panic(err)
+	}
+}
+
+// sortPreferred rebuilds the media types offered during negotiation ordered by priority, then
+// registration order with a canonical media type before its aliases; error only encodings aren't
+// offered. The lock must be held.
+func (r *encodingRegistry) sortPreferred() {
+	r.preferred = r.preferred[:0]
+	for mediaType, reg := range r.byType {
+		if !reg.errorOnly {
+			r.preferred = append(r.preferred, mediaType)
+		}
+	}
+	sort.Slice(r.preferred, func(i, j int) bool {
+		regI, regJ := r.byType[r.preferred[i]], r.byType[r.preferred[j]]
+		if regI.priority != regJ.priority {
+			return regI.priority > regJ.priority
+		}
+		if regI.order != regJ.order {
+			return regI.order < regJ.order
+		}
+		if canonicalI := regI.mediaType == r.preferred[i]; canonicalI != (regJ.mediaType == r.preferred[j]) {
+			return canonicalI
+		}
+		return strings.Compare(r.preferred[i], r.preferred[j]) < 0
+	})
+}
+
+// lookup returns the registered encoding for the media type or nil if there is none.
+func (r *encodingRegistry) lookup(mediaType string) *registeredEncoding {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.byType[mediaType]
+}
+
+// SupportedEncodings returns the registered media types, including aliases, in order of preference;
+// error only encodings aren't included.
+func SupportedEncodings() []string {
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+	return append([]string(nil), registry.preferred...)
+}
+
+// GetStreamEncoder returns the StreamEncoder for the said media type or nil if it has none.
+func GetStreamEncoder(mediaType string) StreamEncoder {
+	if reg := registry.lookup(mediaType); reg != nil {
+		return reg.stream
+	}
+	return nil
+}
+
+// NB: VehicleList collections are encoded as bare arrays by JSON, YAML, MessagePack and CBOR, see
+// proto/vehicle_list.go, while XML and protobuf can't represent one and encode the VehicleList itself.
+func init() {
+	MustRegisterEncoding(ContentAppJSON, &JSONEncoding{}, WithPriority(100))
+	MustRegisterEncoding(ContentAppXML, &XMLEncoding{}, WithPriority(90),
+		WithAliases("text/xml"))
+	MustRegisterEncoding(ContentAppProtobuf, &ProtobufEncoding{}, WithPriority(80),
+		WithAliases("application/protobuf", "application/vnd.google.protobuf"))
+	MustRegisterEncoding(ContentAppYAML, &YAMLEncoding{}, WithPriority(70),
+		WithAliases("application/x-yaml", "text/yaml"))
+	MustRegisterEncoding(ContentAppMsgpack, &MsgpackEncoding{}, WithPriority(60),
+		WithAliases("application/x-msgpack"))
+	MustRegisterEncoding(ContentAppCBOR, &CBOREncoding{}, WithPriority(50))
+	MustRegisterEncoding(ContentAppProblemJSON, &JSONEncoding{}, WithErrorsOnly())
+	MustRegisterEncoding(ContentAppProblemXML, &XMLEncoding{}, WithErrorsOnly())
+}
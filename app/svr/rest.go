@@ -2,23 +2,28 @@
 package svr
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
-	"os"
 	"time"
 
 	"github.com/bodenr/vehicle-api/config"
+	"github.com/bodenr/vehicle-api/metrics"
 	"github.com/bodenr/vehicle-api/svr/proto"
+	"github.com/bodenr/vehicle-api/tracing"
 
 	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/hlog"
+	"golang.org/x/net/http2"
 
 	"github.com/bodenr/vehicle-api/log"
 	"github.com/bodenr/vehicle-api/util"
-
-	protobuf "github.com/golang/protobuf/proto"
 )
 
 // StoreError is a store specific error that can also include a status code hint.
@@ -33,6 +38,10 @@ type StoreError struct {
 // RestServer wraps a http.Server reference.
 type RestServer struct {
 	*http.Server
+	listener         net.Listener
+	reloader         *CertReloader
+	redirect         *http.Server
+	redirectListener net.Listener
 }
 
 // RequestVars is a map of string to string values representing the mux.Vars for a request.
@@ -40,29 +49,35 @@ type RequestVars map[string]string
 
 // StoredResource encapsulates the logic for a API accessible resource.
 type StoredResource interface {
+	// Name returns the name of the resource, e.g. vehicles, as used by authorization policies.
+	Name() string
+
 	// CreateSchema creates the datastore schema for the resource.
-	CreateSchema()
+	CreateSchema() error
 
 	// Search for resources given the said query values.
-	Search(url.Values) ([]interface{}, *StoreError)
+	Search(context.Context, url.Values) ([]interface{}, *StoreError)
 
 	// List all stored resources.
-	List() ([]interface{}, *StoreError)
+	List(context.Context) ([]interface{}, *StoreError)
 
 	// Get a single stored resource based on the request vars.
-	Get(RequestVars) (interface{}, *StoreError)
+	Get(context.Context, RequestVars) (interface{}, *StoreError)
 
-	// Delete a single stored resource based on the request vars.
-	Delete(RequestVars) *StoreError
+	// Delete a single stored resource based on the request vars; if an ETag is given the resource is
+	// only deleted if it still has the ETag, otherwise a 412 StoreError is returned.
+	Delete(ctx context.Context, requestVars RequestVars, etag string) *StoreError
 
 	// Create a new stored resource.
-	Create(interface{}) (interface{}, *StoreError)
+	Create(context.Context, interface{}) (interface{}, *StoreError)
 
-	// Update an existing stored resource.
-	Update(interface{}, RequestVars) (interface{}, *StoreError)
+	// Update an existing stored resource; if an ETag is given the resource is only updated if it
+	// still has the ETag, otherwise a 412 StoreError is returned.
+	Update(ctx context.Context, resource interface{}, requestVars RequestVars,
+		etag string) (interface{}, *StoreError)
 
 	// GetETag returns the eTag for a single resource based on request vars.
-	GetETag(RequestVars) (string, *StoreError)
+	GetETag(context.Context, RequestVars) (string, *StoreError)
 
 	// BuildETag returns the eTag for the said stored resource.
 	BuildETag(interface{}) (string, error)
@@ -73,16 +88,25 @@ type StoredResource interface {
 	// Marshal the said resource into the said content type.
 	Marshal(contentType string, resource interface{}) ([]byte, error)
 
+	// Collection wraps the said resources into a single resource that can be marshalled by any encoding.
+	Collection(resources []interface{}) interface{}
+
 	// Validate the said resource prior to create/update.
 	Validate(resource interface{}, httpMethod string) error
 
-	// Bind the stored resource routes into the router.
-	BindRoutes(router *mux.Router)
+	// Bind the stored resource routes into the router using the said handler.
+	BindRoutes(router *mux.Router, handler RestfulHandler)
 }
 
 // RestfulResource wraps a StoredResource.
 type RestfulResource struct {
 	Resource StoredResource
+
+	// ErrorFormat is the format used for error responses; one of the config.ErrorFormat values.
+	ErrorFormat string
+
+	// Policy authorizes requests; all requests are allowed if nil.
+	Policy *Policy
 }
 
 // RestfulHandler provides the methods supporting REST API handling for a StoredResource.
@@ -107,9 +131,8 @@ type RestfulHandler interface {
 	// Respond to the request with the given code and optional payload.
 	Respond(writer http.ResponseWriter, request *http.Request, code int, payload interface{})
 
-	// Respond to the request with an ErrorResponse.
-	RespondErr(writer http.ResponseWriter, request *http.Request,
-		code int, respErr proto.ErrorResponse)
+	// Respond to the request with an error response for the given code and optional error.
+	RespondErr(writer http.ResponseWriter, request *http.Request, code int, err error)
 
 	// Respond to the request with the given etag, code, and optional payload.
 	RespondETag(writer http.ResponseWriter,
@@ -119,49 +142,69 @@ type RestfulHandler interface {
 // ETagExpires is a time in the distant past used on Expires header to disable time based caching.
 var ETagExpires = util.TimeFromMillis(0).String()
 
-// NewRestfulResource creates a new RestfulResource for the said StoredResource
-func NewRestfulResource(storedResource StoredResource) RestfulResource {
+// ErrUnsupportedMediaType is the error used when a request body has an unsupported content type.
+var ErrUnsupportedMediaType = errors.New("Unsupported request content type")
+
+// ErrETagMismatch is the error used when a request ETag doesn't match the resource.
+var ErrETagMismatch = errors.New("Resource ETag doesn't match If-None-Match")
+
+// NewRestfulResource creates a new RestfulResource for the said StoredResource and config.
+func NewRestfulResource(storedResource StoredResource, conf *config.HTTPConfig) RestfulResource {
 	return RestfulResource{
-		Resource: storedResource,
+		Resource:    storedResource,
+		ErrorFormat: conf.ErrorFormat,
 	}
 }
 
+// authorize checks the request is allowed the said verb on the resource, responding with an error
+// and returning false if denied.
+func (handler RestfulResource) authorize(writer http.ResponseWriter, request *http.Request, verb string) bool {
+	if sErr := handler.Policy.Authorize(request.Context(), handler.Resource.Name(), verb); sErr != nil {
+		handler.RespondErr(writer, request, sErr.StatusCode, sErr.Error)
+		return false
+	}
+	return true
+}
+
 // Create handles the REST API logic to create its underlying StoredResource.
 func (handler RestfulResource) Create(writer http.ResponseWriter, request *http.Request) {
+	if !handler.authorize(writer, request, VerbCreate) {
+		return
+	}
 	// TODO: enforce max size
 	body, err := ioutil.ReadAll(request.Body)
 	if err != nil {
-		log.Log.Err(err).Msg("Error ready request body")
-		handler.Respond(writer, request, http.StatusBadRequest, nil)
+		log.FromContext(request.Context()).Err(err).Msg("Error ready request body")
+		handler.RespondErr(writer, request, http.StatusBadRequest, err)
 		return
 	}
 	contentType := GetRequestContentType(request)
 	if contentType == "" {
-		handler.Respond(writer, request, http.StatusUnsupportedMediaType, nil)
+		handler.RespondErr(writer, request, http.StatusUnsupportedMediaType, ErrUnsupportedMediaType)
 		return
 	}
 	// TODO: better validation/sanitization
 	resource, err := handler.Resource.Unmarshal(contentType, body)
 	if err != nil {
-		log.Log.Err(err).Msg("Invalid resource request body")
-		handler.RespondErr(writer, request, http.StatusBadRequest, proto.ErrorResponse{Message: err.Error()})
+		log.FromContext(request.Context()).Err(err).Msg("Invalid resource request body")
+		handler.RespondErr(writer, request, http.StatusBadRequest, err)
 		return
 	}
 	if err = handler.Resource.Validate(resource, request.Method); err != nil {
-		log.Log.Err(err).Msg("Invalid resource format")
-		handler.RespondErr(writer, request, http.StatusBadRequest, proto.ErrorResponse{Message: err.Error()})
+		log.FromContext(request.Context()).Err(err).Msg("Invalid resource format")
+		handler.RespondErr(writer, request, http.StatusBadRequest, err)
 		return
 	}
 
-	resource, sErr := handler.Resource.Create(resource)
+	resource, sErr := handler.Resource.Create(request.Context(), resource)
 	if sErr != nil {
-		handler.RespondErr(writer, request, sErr.StatusCode, proto.ErrorResponse{Message: sErr.Error.Error()})
+		handler.RespondErr(writer, request, sErr.StatusCode, sErr.Error)
 		return
 	}
 
 	etag, err := handler.Resource.BuildETag(resource)
 	if err != nil {
-		log.Log.Err(err).Msg("Failed to build etag")
+		log.FromContext(request.Context()).Err(err).Msg("Failed to build etag")
 		handler.Respond(writer, request, http.StatusOK, resource)
 		return
 	}
@@ -170,52 +213,35 @@ func (handler RestfulResource) Create(writer http.ResponseWriter, request *http.
 
 // Create handles the REST API logic to list its underlying StoredResources.
 func (handler RestfulResource) List(writer http.ResponseWriter, request *http.Request) {
+	if !handler.authorize(writer, request, VerbList) {
+		return
+	}
 	var err *StoreError
 	var resources []interface{}
 
 	queryParams := request.URL.Query()
 	if len(queryParams) == 0 {
-		resources, err = handler.Resource.List()
+		resources, err = handler.Resource.List(request.Context())
 	} else {
-		resources, err = handler.Resource.Search(queryParams)
+		resources, err = handler.Resource.Search(request.Context(), queryParams)
 	}
 
 	if err != nil {
-		handler.RespondErr(writer, request, err.StatusCode,
-			proto.ErrorResponse{Message: err.Error.Error()})
+		handler.RespondErr(writer, request, err.StatusCode, err.Error)
 		return
 	}
-	handler.Respond(writer, request, http.StatusOK, resources)
+	handler.Respond(writer, request, http.StatusOK, handler.Resource.Collection(resources))
 }
 
 // Create handles the REST API logic to delete its underlying StoredResource.
 func (handler RestfulResource) Delete(writer http.ResponseWriter, request *http.Request) {
-	// TODO: collapse etag handling into shared method
-	requestVars := mux.Vars(request)
-	reqETag := request.Header.Get("If-None-Match")
-	if reqETag != "" {
-		resourceETag, sErr := handler.Resource.GetETag(requestVars)
-		if sErr != nil {
-			if sErr.StatusCode == http.StatusNotFound {
-				handler.Respond(writer, request, http.StatusNotFound, nil)
-				return
-			}
-			handler.RespondErr(writer, request, sErr.StatusCode, proto.ErrorResponse{Message: sErr.Error.Error()})
-			return
-		}
-		if reqETag != resourceETag {
-			handler.Respond(writer, request, http.StatusPreconditionFailed, nil)
-			return
-		}
+	if !handler.authorize(writer, request, VerbDelete) {
+		return
 	}
-
-	err := handler.Resource.Delete(mux.Vars(request))
+	// NB: the store checks the ETag as part of the delete so concurrent writes can't both pass it
+	err := handler.Resource.Delete(request.Context(), mux.Vars(request), request.Header.Get("If-None-Match"))
 	if err != nil {
-		if err.StatusCode == http.StatusNotFound {
-			handler.Respond(writer, request, err.StatusCode, nil)
-			return
-		}
-		handler.RespondErr(writer, request, err.StatusCode, proto.ErrorResponse{Message: err.Error.Error()})
+		handler.RespondErr(writer, request, err.StatusCode, err.Error)
 		return
 	}
 	handler.Respond(writer, request, http.StatusNoContent, nil)
@@ -223,16 +249,15 @@ func (handler RestfulResource) Delete(writer http.ResponseWriter, request *http.
 
 // Create handles the REST API logic to get a specific underlying StoredResource.
 func (handler RestfulResource) Get(writer http.ResponseWriter, request *http.Request) {
+	if !handler.authorize(writer, request, VerbGet) {
+		return
+	}
 	requestVars := mux.Vars(request)
 	reqETag := request.Header.Get("If-None-Match")
 	if reqETag != "" {
-		resourceETag, sErr := handler.Resource.GetETag(requestVars)
+		resourceETag, sErr := handler.Resource.GetETag(request.Context(), requestVars)
 		if sErr != nil {
-			if sErr.StatusCode == http.StatusNotFound {
-				handler.Respond(writer, request, http.StatusNotFound, nil)
-				return
-			}
-			handler.RespondErr(writer, request, sErr.StatusCode, proto.ErrorResponse{Message: sErr.Error.Error()})
+			handler.RespondErr(writer, request, sErr.StatusCode, sErr.Error)
 			return
 		}
 		if reqETag == resourceETag {
@@ -241,19 +266,15 @@ func (handler RestfulResource) Get(writer http.ResponseWriter, request *http.Req
 		}
 	}
 
-	resource, sErr := handler.Resource.Get(mux.Vars(request))
+	resource, sErr := handler.Resource.Get(request.Context(), requestVars)
 	if sErr != nil {
-		if sErr.StatusCode == http.StatusNotFound {
-			handler.Respond(writer, request, sErr.StatusCode, nil)
-			return
-		}
-		handler.RespondErr(writer, request, sErr.StatusCode, proto.ErrorResponse{Message: sErr.Error.Error()})
+		handler.RespondErr(writer, request, sErr.StatusCode, sErr.Error)
 		return
 	}
 
 	etag, err := handler.Resource.BuildETag(resource)
 	if err != nil {
-		log.Log.Err(err).Msg("Failed to build etag")
+		log.FromContext(request.Context()).Err(err).Msg("Failed to build etag")
 		handler.Respond(writer, request, http.StatusOK, resource)
 		return
 	}
@@ -262,56 +283,43 @@ func (handler RestfulResource) Get(writer http.ResponseWriter, request *http.Req
 
 // Create handles the REST API logic to update a specific underlying StoredResource.
 func (handler RestfulResource) Update(writer http.ResponseWriter, request *http.Request) {
-	requestVars := mux.Vars(request)
-	reqETag := request.Header.Get("If-None-Match")
-	if reqETag != "" {
-		resourceETag, sErr := handler.Resource.GetETag(requestVars)
-		if sErr != nil {
-			if sErr.StatusCode == http.StatusNotFound {
-				handler.Respond(writer, request, http.StatusNotFound, nil)
-				return
-			}
-			handler.RespondErr(writer, request, sErr.StatusCode, proto.ErrorResponse{Message: sErr.Error.Error()})
-			return
-		}
-		if reqETag != resourceETag {
-			handler.Respond(writer, request, http.StatusPreconditionFailed, nil)
-			return
-		}
+	if !handler.authorize(writer, request, VerbUpdate) {
+		return
 	}
-
 	// TODO: enforce max size
 	body, err := ioutil.ReadAll(request.Body)
 	if err != nil {
-		log.Log.Err(err).Msg("Error reading request body")
-		handler.Respond(writer, request, http.StatusInternalServerError, nil)
+		log.FromContext(request.Context()).Err(err).Msg("Error reading request body")
+		handler.RespondErr(writer, request, http.StatusInternalServerError, err)
 		return
 	}
 	contentType := GetRequestContentType(request)
 	if contentType == "" {
-		handler.Respond(writer, request, http.StatusUnsupportedMediaType, nil)
+		handler.RespondErr(writer, request, http.StatusUnsupportedMediaType, ErrUnsupportedMediaType)
 		return
 	}
 	// TODO: better validation
 	resource, err := handler.Resource.Unmarshal(contentType, body)
 	if err != nil {
-		log.Log.Err(err).Msg("Error unmarshalling request body")
-		handler.RespondErr(writer, request, http.StatusBadRequest, proto.ErrorResponse{Message: err.Error()})
+		log.FromContext(request.Context()).Err(err).Msg("Error unmarshalling request body")
+		handler.RespondErr(writer, request, http.StatusBadRequest, err)
 		return
 	}
 	if err = handler.Resource.Validate(resource, request.Method); err != nil {
-		log.Log.Err(err).Msg("Invalid body format")
-		handler.RespondErr(writer, request, http.StatusBadRequest, proto.ErrorResponse{Message: err.Error()})
+		log.FromContext(request.Context()).Err(err).Msg("Invalid body format")
+		handler.RespondErr(writer, request, http.StatusBadRequest, err)
 		return
 	}
-	resource, sErr := handler.Resource.Update(resource, mux.Vars(request))
+	// NB: the store checks the ETag as part of the update so concurrent writes can't both pass it
+	resource, sErr := handler.Resource.Update(request.Context(), resource, mux.Vars(request),
+		request.Header.Get("If-None-Match"))
 	if sErr != nil {
-		handler.RespondErr(writer, request, sErr.StatusCode, proto.ErrorResponse{Message: sErr.Error.Error()})
+		handler.RespondErr(writer, request, sErr.StatusCode, sErr.Error)
 		return
 	}
 	etag, err := handler.Resource.BuildETag(resource)
 	if err != nil {
-		log.Log.Err(err).Msg("Failed to build etag")
+		log.FromContext(request.Context()).Err(err).Msg("Failed to build etag")
 		handler.Respond(writer, request, http.StatusOK, resource)
 		return
 	}
@@ -329,24 +337,41 @@ func (handler RestfulResource) RespondETag(writer http.ResponseWriter,
 	handler.Respond(writer, request, code, payload)
 }
 
-// RespondErr to the request with an ErrorResponse.
+// RespondErr to the request with a Problem, or an ErrorResponse when using the legacy error format.
 func (handler RestfulResource) RespondErr(writer http.ResponseWriter, request *http.Request,
-	code int, respErr proto.ErrorResponse) {
+	code int, err error) {
 
-	// TODO: special handling for protobuf error marshalling sux
-	if GetResponseContentType(request) == ContentAppProtobuf {
-		responseBody, err := protobuf.Marshal(&respErr)
-		if err != nil {
-			log.Log.Err(err).Msg("Error marshalling protobuf")
-			writer.WriteHeader(http.StatusInternalServerError)
+	respondErr(writer, request, handler.ErrorFormat, code, err)
+}
+
+// respondErr responds to the request with an error response in the said error format; JSON is used
+// if the request accepts none of the supported encodings.
+func respondErr(writer http.ResponseWriter, request *http.Request, errorFormat string, code int, err error) {
+	contentType := GetResponseContentType(request)
+	if contentType == "" {
+		contentType = ContentAppJSON
+	}
+	var payload interface{}
+	if errorFormat == config.ErrorFormatLegacy {
+		if err == nil {
+			writer.WriteHeader(code)
 			return
 		}
-		writer.Header().Set("Content-Type", ContentAppProtobuf)
-		writer.WriteHeader(code)
-		writer.Write(responseBody)
+		payload = proto.ErrorResponse{Message: err.Error()}
 	} else {
-		handler.Respond(writer, request, code, respErr)
+		payload = NewProblem(request, code, err)
+		contentType = ProblemContentType(contentType)
+	}
+
+	responseBody, mErr := Marshal(contentType, payload)
+	if mErr != nil {
+		log.FromContext(request.Context()).Err(mErr).Msg("Error marshalling error response")
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
 	}
+	writer.Header().Set("Content-Type", contentType)
+	writer.WriteHeader(code)
+	writer.Write(responseBody)
 }
 
 // Respond to the http request with the said status code and optional payload.
@@ -355,10 +380,21 @@ func (handler RestfulResource) Respond(writer http.ResponseWriter,
 
 	if payload != nil {
 		contentType := GetResponseContentType(request)
-		responseBody, err := handler.Resource.Marshal(contentType, payload)
+		var responseBody []byte
+		var err error
+		// NB: stream encoders write to a buffer so errors aren't sent after a successful status
+		if stream := GetStreamEncoder(contentType); stream != nil {
+			var buf bytes.Buffer
+			err = stream.Encode(&buf, payload)
+			responseBody = buf.Bytes()
+		} else {
+			responseBody, err = handler.Resource.Marshal(contentType, payload)
+		}
 		if err != nil {
-			responseBody, _ = Marshal(contentType, proto.ErrorResponse{Message: "Error marshalling response body"})
-			code = http.StatusInternalServerError
+			log.FromContext(request.Context()).Err(err).Msg("Error marshalling response body")
+			handler.RespondErr(writer, request, http.StatusInternalServerError,
+				errors.New("Error marshalling response body"))
+			return
 		}
 		writer.Header().Set("Content-Type", contentType)
 		writer.WriteHeader(code)
@@ -368,71 +404,229 @@ func (handler RestfulResource) Respond(writer http.ResponseWriter,
 	}
 }
 
-// NewRestServer creates a new RestServer for the given config that will expose the given StoredResources.
-func NewRestServer(conf *config.HTTPConfig, storedResources ...StoredResource) *RestServer {
-
-	router := mux.NewRouter()
-
-	subrouter := router.PathPrefix("/api").Subrouter()
+// refererHandler adds the referer of requests to the request logger under the said key, with
+// sensitive query params redacted.
+func refererHandler(fieldKey string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			if referer := request.Referer(); referer != "" {
+				hlog.FromRequest(request).UpdateContext(func(c zerolog.Context) zerolog.Context {
+					return c.Str(fieldKey, log.RedactURL(referer))
+				})
+			}
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
 
-	subrouter.Use(hlog.NewHandler(log.Log))
+// useAccessLog adds the request logger and access log middleware to the router, so each request is
+// logged with its correlation fields, e.g. req_id. Access log entries are subject to the log sampling
+// if sampled, otherwise every request is logged.
+func useAccessLog(router *mux.Router, sampled bool) {
+	router.Use(hlog.NewHandler(log.Log))
 
-	subrouter.Use(hlog.AccessHandler(
+	router.Use(hlog.AccessHandler(
 		func(r *http.Request, status, size int, duration time.Duration) {
-			hlog.FromRequest(r).Info().
+			logger := hlog.FromRequest(r)
+			if sampled {
+				logger = log.Sampled(logger)
+			}
+			logger.Info().
 				Str("method", r.Method).
-				Str("url", r.URL.String()).
+				Str("url", log.RedactURL(r.URL.String())).
 				Int("status", status).
 				Int("size", size).
 				Dur("duration", duration).
 				Msg("")
 		}))
 
-	subrouter.Use(hlog.RemoteAddrHandler("ip"))
-	subrouter.Use(hlog.UserAgentHandler("user_agent"))
-	subrouter.Use(hlog.RefererHandler("referer"))
-	subrouter.Use(hlog.RequestIDHandler("req_id", "Request-Id"))
+	router.Use(hlog.RemoteAddrHandler(log.IP))
+	router.Use(hlog.UserAgentHandler(log.UserAgent))
+	router.Use(refererHandler("referer"))
+	router.Use(hlog.RequestIDHandler(log.RequestID, "Request-Id"))
+}
+
+// NewRestServer creates a new RestServer for the given config that will expose the given StoredResources.
+// Requests aren't authenticated if auth is nil; API requests are scoped to the tenant resolved by
+// tenancy.
+func NewRestServer(conf *config.HTTPConfig, health *Health, auth *Auth, tenancy *Tenancy,
+	storedResources ...StoredResource) (*RestServer, error) {
+
+	switch conf.ErrorFormat {
+	case "", config.ErrorFormatProblem, config.ErrorFormatLegacy:
+	default:
+		return nil, fmt.Errorf("Invalid HTTP error format %s; must be one of: %s, %s",
+			conf.ErrorFormat, config.ErrorFormatProblem, config.ErrorFormatLegacy)
+	}
+
+	router := mux.NewRouter()
+
+	subrouter := router.PathPrefix("/api").Subrouter()
+
+	useAccessLog(subrouter, true)
+	subrouter.Use(tracing.HTTPHandler)
+	subrouter.Use(metrics.HTTPHandler)
+	subrouter.Use(NegotiationHandler(conf.ErrorFormat))
+	if auth != nil {
+		subrouter.Use(auth.httpHandler(conf.ErrorFormat, auth.Authenticate))
+	}
+	subrouter.Use(tenancy.httpHandler(conf.ErrorFormat))
 
 	for _, resource := range storedResources {
-		resource.BindRoutes(subrouter)
+		handler := NewRestfulResource(resource, conf)
+		if auth != nil {
+			handler.Policy = auth.policy
+		}
+		resource.BindRoutes(subrouter, handler)
 	}
 
-	return &RestServer{
+	if health != nil {
+		health.BindRoutes(router)
+	}
+	metrics.BindRoutes(router)
+	if conf.Descriptors {
+		router.HandleFunc("/descriptors", DescriptorSetHandler).Methods(http.MethodGet)
+	}
+	if conf.Admin {
+		adminRouter := router.PathPrefix(AdminPathPrefix).Subrouter()
+		// NB: admin requests are never sampled so every change, e.g. of the log level, is logged
+		useAccessLog(adminRouter, false)
+		if auth != nil {
+			adminRouter.Use(auth.httpHandler(conf.ErrorFormat, auth.AuthenticateAdmin))
+			if auth.keys != nil {
+				auth.BindRoutes(adminRouter)
+			}
+		}
+		BindAdminRoutes(adminRouter)
+		if auth == nil {
+			log.Log.Warn().Msg("admin endpoints are enabled without authentication")
+		}
+	}
+
+	server := &RestServer{
 		Server: &http.Server{
 			Addr:         conf.Address,
-			Handler:      subrouter,
+			Handler:      router,
 			ReadTimeout:  15 * time.Second,
 			WriteTimeout: 15 * time.Second,
 			IdleTimeout:  60 * time.Second,
 		},
 	}
+	if conf.TLS.Enabled() {
+		tlsConf, reloader, err := NewTLSConfig(&conf.TLS)
+		if err != nil {
+			return nil, err
+		}
+		server.TLSConfig = tlsConf
+		server.reloader = reloader
+		// NB: fails if the configured cipher suites don't include one required by HTTP/2
+		if err = http2.ConfigureServer(server.Server, nil); err != nil {
+			reloader.Stop()
+			return nil, err
+		}
+		if conf.RedirectAddress != "" {
+			server.redirect = newRedirectServer(conf.RedirectAddress, conf.Address)
+		}
+		log.Log.Info().Str(log.CertFile, conf.TLS.CertFile).Msg("enabled http tls")
+	}
+	return server, nil
 }
 
-// Run starts the RestServer and is blocking in nature.
-func (server *RestServer) Run() error {
-	log.Log.Info().Msg("starting http server on port " + server.Addr)
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Log.Err(err).Msg("failed to start http server")
+// newRedirectServer creates a plaintext server on the said address permanently redirecting requests
+// to HTTPS on the port of the TLS address.
+func newRedirectServer(address, tlsAddress string) *http.Server {
+	_, port, _ := net.SplitHostPort(tlsAddress)
+	return &http.Server{
+		Addr: address,
+		Handler: http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			host := request.Host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			if port != "" && port != "443" {
+				host = net.JoinHostPort(host, port)
+			}
+			target := url.URL{
+				Scheme:   "https",
+				Host:     host,
+				Path:     request.URL.Path,
+				RawQuery: request.URL.RawQuery,
+			}
+			http.Redirect(writer, request, target.String(), http.StatusPermanentRedirect)
+		}),
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 5 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+}
+
+// Name returns the component name of the RestServer.
+func (server *RestServer) Name() string {
+	return "http"
+}
+
+// Start creates the listeners of the RestServer.
+func (server *RestServer) Start(context.Context) error {
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
 		return err
 	}
+	if server.redirect != nil {
+		if server.redirectListener, err = net.Listen("tcp", server.redirect.Addr); err != nil {
+			listener.Close()
+			return err
+		}
+	}
+	server.listener = listener
 	return nil
 }
 
-// WaitForShutdown waits for a signal on terminate and then shutsdown the RestServer.
-func (server *RestServer) WaitForShutdown(terminate <-chan os.Signal, done chan bool) {
-	termSig := <-terminate
-
-	log.Log.Info().Str(log.Signal, termSig.String()).Msg("shutting down http server")
+// Run serves the RestServer on the listeners created by Start and is blocking in nature.
+func (server *RestServer) Run() error {
+	if server.redirect != nil {
+		go func() {
+			log.Log.Info().Msg("starting http redirect server on port " + server.redirect.Addr)
+			err := server.redirect.Serve(server.redirectListener)
+			if err != nil && err != http.ErrServerClosed {
+				log.Log.Err(err).Msg("failed to start http redirect server")
+			}
+		}()
+	}
+	return server.Serve(server.listener)
+}
 
-	ctx, halt := context.WithTimeout(context.Background(), 20*time.Second)
-	defer halt()
+// Serve starts the RestServer on the said listener and is blocking in nature.
+func (server *RestServer) Serve(listener net.Listener) error {
+	log.Log.Info().Msg("starting http server on " + listener.Addr().String())
+	var err error
+	if server.TLSConfig != nil {
+		// NB: certificates are served from the TLSConfig
+		err = server.ServeTLS(listener, "", "")
+	} else {
+		err = server.Server.Serve(listener)
+	}
+	if err != nil && err != http.ErrServerClosed {
+		log.Log.Err(err).Msg("failed to start http server")
+		return err
+	}
+	return nil
+}
 
+// Stop gracefully shuts down the RestServer, waiting for active connections until the context is done.
+func (server *RestServer) Stop(ctx context.Context) error {
 	server.SetKeepAlivesEnabled(false)
+	if server.redirect != nil {
+		if err := server.redirect.Shutdown(ctx); err != nil {
+			log.Log.Err(err).Msg("failed to gracefully stop http redirect server")
+		}
+	}
+	if server.reloader != nil {
+		defer server.reloader.Stop()
+	}
 	if err := server.Shutdown(ctx); err != nil {
 		log.Log.Err(err).Msg("failed to gracefully stop http server")
-	} else {
-		log.Log.Info().Msg("graceful shutdown of http server complete")
+		return err
 	}
-
-	close(done)
+	log.Log.Info().Msg("graceful shutdown of http server complete")
+	return nil
 }
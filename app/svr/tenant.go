@@ -0,0 +1,193 @@
+package svr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/bodenr/vehicle-api/config"
+	"github.com/bodenr/vehicle-api/log"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/hlog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultTenant is the tenant of unauthenticated requests without a tenant; it's also the
+	// tenant of resources stored before tenants were introduced.
+	DefaultTenant = "default"
+
+	// DefaultTenantHeader is the request header, and GRPC metadata key, holding the tenant by default.
+	DefaultTenantHeader = "x-tenant-id"
+
+	// DefaultTenantClaim is the token claim holding the tenant of principals by default.
+	DefaultTenantClaim = "tenant_id"
+)
+
+var (
+	// ErrInvalidTenant is the error used for malformed tenant IDs.
+	ErrInvalidTenant = errors.New("Invalid tenant ID")
+
+	// ErrMissingTenant is the error used when a resource is accessed without a tenant, which is
+	// refused rather than risking access to the resources of all tenants.
+	ErrMissingTenant = errors.New("Request has no tenant")
+
+	// ErrPrincipalWithoutTenant is the error used when an authenticated principal isn't bound to a
+	// tenant, e.g. a token without the tenant claim.
+	ErrPrincipalWithoutTenant = fmt.Errorf("%w; principal has no tenant", ErrPermissionDenied)
+
+	// tenantPattern matches valid tenant IDs; they fit the VARCHAR(64) tenant columns.
+	tenantPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+)
+
+// tenantFormat describes the format of tenant IDs in validation errors.
+const tenantFormat = "at most 64 letters, digits, dots, dashes or underscores"
+
+// tenantKey is the context key of the tenant.
+type tenantKey struct{}
+
+// WithTenant returns a copy of the context carrying the said tenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant of the request context or an empty string if not set.
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// ValidTenant returns true if the tenant ID is well formed.
+func ValidTenant(tenant string) bool {
+	return tenantPattern.MatchString(tenant)
+}
+
+// Tenancy resolves the tenant of REST and GRPC requests, scoping StoredResource operations to it.
+type Tenancy struct {
+	header            string
+	trustHeader       bool
+	defaultPrincipals bool
+}
+
+// NewTenancy creates the Tenancy for the config.
+func NewTenancy(conf *config.TenantConfig) *Tenancy {
+	header := conf.Header
+	if header == "" {
+		header = DefaultTenantHeader
+	}
+	if conf.TrustHeader {
+		log.Log.Warn().Msg("Unauthenticated requests use the tenant of the tenant header")
+	}
+	if conf.DefaultPrincipals {
+		log.Log.Warn().Msg("Principals without a tenant use the default tenant")
+	}
+	return &Tenancy{header: header, trustHeader: conf.TrustHeader, defaultPrincipals: conf.DefaultPrincipals}
+}
+
+// Resolve returns the tenant of the request given the tenant it requested, if any. Authenticated
+// requests are bound to the tenant of their principal, so requesting another tenant is denied;
+// unauthenticated requests use the DefaultTenant unless the header is trusted, so any caller can't
+// pick a tenant. Principals without a tenant are denied unless they're configured to use the
+// DefaultTenant.
+func (tenancy *Tenancy) Resolve(ctx context.Context, requested string) (string, *StoreError) {
+	principal := PrincipalFromContext(ctx)
+	if principal == nil {
+		if requested == "" {
+			return DefaultTenant, nil
+		}
+		if !tenancy.trustHeader {
+			log.FromContext(ctx).Debug().Msg("Ignored the tenant header of an unauthenticated request")
+			return DefaultTenant, nil
+		}
+		if !ValidTenant(requested) {
+			return "", &StoreError{Error: ErrInvalidTenant, StatusCode: http.StatusBadRequest}
+		}
+		return requested, nil
+	}
+
+	tenant := principal.Tenant
+	if tenant == "" {
+		if !tenancy.defaultPrincipals {
+			log.FromContext(ctx).Warn().Msg("Denied principal without a tenant")
+			return "", &StoreError{Error: ErrPrincipalWithoutTenant, StatusCode: http.StatusForbidden}
+		}
+		tenant = DefaultTenant
+	}
+	if !ValidTenant(tenant) {
+		log.FromContext(ctx).Warn().Str(log.Tenant, tenant).Msg("Principal has an invalid tenant")
+		return "", &StoreError{Error: ErrInvalidTenant, StatusCode: http.StatusForbidden}
+	}
+	if requested != "" && requested != tenant {
+		log.FromContext(ctx).Warn().Str(log.Tenant, requested).Msg("Denied access to another tenant")
+		return "", &StoreError{
+			Error:      fmt.Errorf("%w to tenant %s", ErrPermissionDenied, requested),
+			StatusCode: http.StatusForbidden,
+		}
+	}
+	return tenant, nil
+}
+
+// httpHandler is middleware resolving the tenant of REST requests, responding with an error in the
+// said format if the tenant is invalid or not accessible.
+func (tenancy *Tenancy) httpHandler(errorFormat string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			tenant, sErr := tenancy.Resolve(request.Context(), request.Header.Get(tenancy.header))
+			if sErr != nil {
+				respondErr(writer, request, errorFormat, sErr.StatusCode, sErr.Error)
+				return
+			}
+			hlog.FromRequest(request).UpdateContext(func(c zerolog.Context) zerolog.Context {
+				return c.Str(log.Tenant, tenant)
+			})
+			next.ServeHTTP(writer, request.WithContext(WithTenant(request.Context(), tenant)))
+		})
+	}
+}
+
+// resolveGrpc resolves the tenant of the GRPC request, returning the context with the tenant.
+// Public and admin services aren't scoped to a tenant.
+func (tenancy *Tenancy) resolveGrpc(ctx context.Context, fullMethod string) (context.Context, error) {
+	if isPublicGrpcMethod(fullMethod) || isAdminGrpcMethod(fullMethod) {
+		return ctx, nil
+	}
+	var requested string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(tenancy.header); len(values) > 0 {
+			requested = values[0]
+		}
+	}
+	tenant, sErr := tenancy.Resolve(ctx, requested)
+	if sErr != nil {
+		return nil, status.Error(grpcCode(sErr.StatusCode), sErr.Error.Error())
+	}
+	// NB: the request logger is updated in place so the access log includes the tenant
+	log.UpdateContext(ctx, func(c zerolog.Context) zerolog.Context {
+		return c.Str(log.Tenant, tenant)
+	})
+	return WithTenant(ctx, tenant), nil
+}
+
+// UnaryServerInterceptor resolves the tenant of unary GRPC requests.
+func (tenancy *Tenancy) UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := tenancy.resolveGrpc(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamServerInterceptor resolves the tenant of stream GRPC requests.
+func (tenancy *Tenancy) StreamServerInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	ctx, err := tenancy.resolveGrpc(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+}
@@ -0,0 +1,67 @@
+package svr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/bodenr/vehicle-api/config"
+)
+
+func TestTenancyResolve(t *testing.T) {
+	principal := func(tenant string) context.Context {
+		return WithPrincipal(context.Background(), &Principal{Subject: "key-1", Tenant: tenant})
+	}
+	tests := []struct {
+		name              string
+		ctx               context.Context
+		requested         string
+		trustHeader       bool
+		defaultPrincipals bool
+		tenant            string
+		status            int
+	}{
+		{"unauthenticated", context.Background(), "", false, false, DefaultTenant, 0},
+		{"unauthenticated requested", context.Background(), "dealer-a", false, false, DefaultTenant, 0},
+		{"unauthenticated invalid", context.Background(), "bad tenant", false, false, DefaultTenant, 0},
+		{"unauthenticated trusted", context.Background(), "", true, false, DefaultTenant, 0},
+		{"unauthenticated trusted requested", context.Background(), "dealer-a", true, false, "dealer-a", 0},
+		{"unauthenticated trusted invalid", context.Background(), "bad tenant", true, false, "", http.StatusBadRequest},
+		{"principal", principal("dealer-a"), "", false, false, "dealer-a", 0},
+		{"principal requested own", principal("dealer-a"), "dealer-a", false, false, "dealer-a", 0},
+		{"principal requested other", principal("dealer-a"), "dealer-b", false, false, "", http.StatusForbidden},
+		{"principal trusted requested other", principal("dealer-a"), "dealer-b", true, false, "", http.StatusForbidden},
+		{"principal invalid", principal("bad tenant"), "", false, false, "", http.StatusForbidden},
+		{"principal without tenant", principal(""), "", false, false, "", http.StatusForbidden},
+		{"principal without tenant requested default", principal(""), DefaultTenant, false, false, "", http.StatusForbidden},
+		{"principal without tenant opted in", principal(""), "", false, true, DefaultTenant, 0},
+		{"principal without tenant opted in requested other", principal(""), "dealer-a", false, true, "", http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tenancy := NewTenancy(&config.TenantConfig{
+				TrustHeader:       test.trustHeader,
+				DefaultPrincipals: test.defaultPrincipals,
+			})
+			tenant, sErr := tenancy.Resolve(test.ctx, test.requested)
+			if test.status != 0 {
+				if sErr == nil || sErr.StatusCode != test.status {
+					t.Fatalf("expected status %d, got %v", test.status, sErr)
+				}
+				return
+			}
+			if sErr != nil {
+				t.Fatalf("unexpected error: %v", sErr.Error)
+			}
+			if tenant != test.tenant {
+				t.Fatalf("expected tenant %s, got %s", test.tenant, tenant)
+			}
+		})
+	}
+
+	_, sErr := NewTenancy(&config.TenantConfig{}).Resolve(principal(""), "")
+	if !errors.Is(sErr.Error, ErrPermissionDenied) {
+		t.Fatalf("expected permission denied, got %v", sErr.Error)
+	}
+}
@@ -0,0 +1,273 @@
+package svr
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bodenr/vehicle-api/config"
+	"github.com/bodenr/vehicle-api/log"
+)
+
+// ErrClientNotAllowed is returned when a verified client certificate isn't in the allowlists.
+var ErrClientNotAllowed = errors.New("Client certificate is not allowed")
+
+// tlsVersions maps the supported TLSConfig MinVersion values to TLS versions.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// cipherSuites returns the IDs of the named cipher suites; only suites without known security
+// issues are supported.
+func cipherSuites(names []string) ([]uint16, error) {
+	supported := map[string]uint16{}
+	for _, suite := range tls.CipherSuites() {
+		supported[suite.Name] = suite.ID
+	}
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := supported[name]
+		if !ok {
+			return nil, fmt.Errorf("Unsupported TLS cipher suite %s", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// CertReloader serves the key pair and client CAs of a TLSConfig, reloading them when the files
+// change on disk so rotated certificates are picked up without a restart.
+type CertReloader struct {
+	conf      *config.TLSConfig
+	lock      sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+// NewCertReloader loads the certificates of the TLSConfig and watches them for changes if a reload
+// interval is set.
+func NewCertReloader(conf *config.TLSConfig) (*CertReloader, error) {
+	reloader := &CertReloader{
+		conf: conf,
+		stop: make(chan struct{}),
+	}
+	if err := reloader.reload(); err != nil {
+		return nil, err
+	}
+	if conf.ReloadInterval > 0 {
+		go reloader.watch()
+	}
+	return reloader, nil
+}
+
+// files returns the certificate files being served.
+func (reloader *CertReloader) files() []string {
+	files := []string{reloader.conf.CertFile, reloader.conf.KeyFile}
+	if reloader.conf.CAFile != "" {
+		files = append(files, reloader.conf.CAFile)
+	}
+	return files
+}
+
+// modified returns the modification times of the files if any have changed since the last load.
+func (reloader *CertReloader) modified() (map[string]time.Time, bool) {
+	modTimes := map[string]time.Time{}
+	changed := false
+	for _, file := range reloader.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			// NB: files are briefly missing while a secret volume is being updated
+			return nil, false
+		}
+		modTimes[file] = info.ModTime()
+		reloader.lock.RLock()
+		if !modTimes[file].Equal(reloader.modTimes[file]) {
+			changed = true
+		}
+		reloader.lock.RUnlock()
+	}
+	return modTimes, changed
+}
+
+// reload loads the key pair and client CAs from disk.
+func (reloader *CertReloader) reload() error {
+	modTimes, _ := reloader.modified()
+	cert, err := tls.LoadX509KeyPair(reloader.conf.CertFile, reloader.conf.KeyFile)
+	if err != nil {
+		return fmt.Errorf("Failed to load key pair %s: %v", reloader.conf.CertFile, err)
+	}
+	var clientCAs *x509.CertPool
+	if reloader.conf.CAFile != "" {
+		pem, err := ioutil.ReadFile(reloader.conf.CAFile)
+		if err != nil {
+			return fmt.Errorf("Failed to read CA file %s: %v", reloader.conf.CAFile, err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("No certificates found in CA file %s", reloader.conf.CAFile)
+		}
+	}
+
+	reloader.lock.Lock()
+	defer reloader.lock.Unlock()
+	reloader.cert = &cert
+	reloader.clientCAs = clientCAs
+	reloader.modTimes = modTimes
+	return nil
+}
+
+// watch polls the files for changes until stopped; failed reloads keep serving the previous
+// certificates.
+func (reloader *CertReloader) watch() {
+	ticker := time.NewTicker(reloader.conf.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-reloader.stop:
+			return
+		case <-ticker.C:
+			if _, changed := reloader.modified(); !changed {
+				continue
+			}
+			if err := reloader.reload(); err != nil {
+				log.Log.Err(err).Msg("Failed to reload certificates")
+				continue
+			}
+			log.Log.Info().Str(log.CertFile, reloader.conf.CertFile).Msg("Reloaded certificates")
+		}
+	}
+}
+
+// Stop stops watching the files for changes.
+func (reloader *CertReloader) Stop() {
+	reloader.stopOnce.Do(func() {
+		close(reloader.stop)
+	})
+}
+
+// GetCertificate returns the current key pair; it's used as the tls.Config GetCertificate callback.
+func (reloader *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	reloader.lock.RLock()
+	defer reloader.lock.RUnlock()
+	return reloader.cert, nil
+}
+
+// VerifyClient verifies the client certificate chain against the current client CAs and the
+// subject and SAN allowlists; it's used as the tls.Config VerifyPeerCertificate callback.
+func (reloader *CertReloader) VerifyClient(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		// NB: missing certificates are rejected by the handshake when they're required
+		return nil
+	}
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("Failed to parse client certificate: %v", err)
+		}
+		certs[i] = cert
+	}
+	reloader.lock.RLock()
+	opts := x509.VerifyOptions{
+		Roots:         reloader.clientCAs,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	reloader.lock.RUnlock()
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if _, err := certs[0].Verify(opts); err != nil {
+		return err
+	}
+	if !clientAllowed(reloader.conf, certs[0]) {
+		return ErrClientNotAllowed
+	}
+	return nil
+}
+
+// clientAllowed returns true if the client certificate matches the allowlists of the TLSConfig.
+func clientAllowed(conf *config.TLSConfig, cert *x509.Certificate) bool {
+	if len(conf.AllowedSubjects) == 0 && len(conf.AllowedSANs) == 0 {
+		return true
+	}
+	for _, subject := range conf.AllowedSubjects {
+		if subject == cert.Subject.CommonName || subject == cert.Subject.String() {
+			return true
+		}
+	}
+	sans := append([]string{}, cert.DNSNames...)
+	sans = append(sans, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	for _, allowed := range conf.AllowedSANs {
+		for _, san := range sans {
+			if allowed == san {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NewTLSConfig creates a server tls.Config for the TLSConfig along with the CertReloader serving its
+// certificates; the reloader must be stopped when the server is.
+func NewTLSConfig(conf *config.TLSConfig) (*tls.Config, *CertReloader, error) {
+	if conf.KeyFile == "" {
+		return nil, nil, fmt.Errorf("No TLS key file given for certificate %s", conf.CertFile)
+	}
+	tlsConf := &tls.Config{}
+	if conf.MinVersion != "" {
+		version, ok := tlsVersions[conf.MinVersion]
+		if !ok {
+			return nil, nil, fmt.Errorf("Invalid TLS min version %s", conf.MinVersion)
+		}
+		tlsConf.MinVersion = version
+	}
+	if len(conf.CipherSuites) > 0 {
+		suites, err := cipherSuites(conf.CipherSuites)
+		if err != nil {
+			return nil, nil, err
+		}
+		tlsConf.CipherSuites = suites
+	}
+	switch conf.ClientAuth {
+	case "", config.ClientAuthNone:
+		tlsConf.ClientAuth = tls.NoClientCert
+	case config.ClientAuthOptional:
+		tlsConf.ClientAuth = tls.RequestClientCert
+	case config.ClientAuthRequire:
+		tlsConf.ClientAuth = tls.RequireAnyClientCert
+	default:
+		return nil, nil, fmt.Errorf("Invalid TLS client auth %s", conf.ClientAuth)
+	}
+	if tlsConf.ClientAuth != tls.NoClientCert && conf.CAFile == "" {
+		return nil, nil, fmt.Errorf("A TLS CA file is required to verify client certificates")
+	}
+
+	reloader, err := NewCertReloader(conf)
+	if err != nil {
+		return nil, nil, err
+	}
+	tlsConf.GetCertificate = reloader.GetCertificate
+	if tlsConf.ClientAuth != tls.NoClientCert {
+		// NB: client certificates are verified by the reloader so CA changes are picked up
+		tlsConf.VerifyPeerCertificate = reloader.VerifyClient
+	}
+	return tlsConf, reloader, nil
+}
@@ -0,0 +1,201 @@
+// Package tracing provides OpenTelemetry tracing for the application.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/bodenr/vehicle-api/config"
+	"github.com/bodenr/vehicle-api/log"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/hlog"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpgrpc"
+	"go.opentelemetry.io/otel/exporters/stdout"
+	"go.opentelemetry.io/otel/propagation"
+	exporttrace "go.opentelemetry.io/otel/sdk/export/trace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+const (
+	// DefaultServiceName is the default service name of spans.
+	DefaultServiceName = "vehicle-api"
+
+	// tracerName is the name of the tracer used for application spans.
+	tracerName = "github.com/bodenr/vehicle-api"
+)
+
+// provider is nil unless tracing is enabled; file is the span file of the file exporter.
+var (
+	provider    *sdktrace.TracerProvider
+	file        io.Closer
+	serviceName = DefaultServiceName
+	lock        sync.Mutex
+)
+
+func init() {
+	// NB: context is propagated even when tracing is disabled so the trace of callers isn't broken
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// newExporter creates the span exporter for the config.
+func newExporter(conf *config.TracingConfig) (exporttrace.SpanExporter, io.Closer, error) {
+	switch conf.Exporter {
+	case config.TracingExporterStdout:
+		exporter, err := stdout.NewExporter(stdout.WithoutMetricExport())
+		return exporter, nil, err
+	case config.TracingExporterFile:
+		if conf.File == "" {
+			return nil, nil, fmt.Errorf("No file set for the %s tracing exporter", conf.Exporter)
+		}
+		f, err := os.OpenFile(conf.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, nil, err
+		}
+		exporter, err := stdout.NewExporter(stdout.WithWriter(f), stdout.WithoutMetricExport())
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return exporter, f, nil
+	case config.TracingExporterOTLP:
+		opts := []otlpgrpc.Option{}
+		if conf.OTLPEndpoint != "" {
+			opts = append(opts, otlpgrpc.WithEndpoint(conf.OTLPEndpoint))
+		}
+		if conf.OTLPInsecure {
+			opts = append(opts, otlpgrpc.WithInsecure())
+		}
+		// NB: the exporter connects in the background so the collector needn't be up on start
+		exporter, err := otlp.NewExporter(context.Background(), otlpgrpc.NewDriver(opts...))
+		return exporter, nil, err
+	}
+	return nil, nil, fmt.Errorf("Unsupported tracing exporter: %s", conf.Exporter)
+}
+
+// Init sets up the global tracer provider as per the config; spans aren't recorded unless Init is
+// called with an enabled config.
+func Init(conf *config.TracingConfig) error {
+	if !conf.Enabled() {
+		return nil
+	}
+	exporter, closer, err := newExporter(conf)
+	if err != nil {
+		return err
+	}
+	name := conf.ServiceName
+	if name == "" {
+		name = DefaultServiceName
+	}
+
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithConfig(sdktrace.Config{
+			DefaultSampler: sdktrace.ParentBased(sdktrace.TraceIDRatioBased(conf.SampleRatio)),
+		}),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.ServiceNameKey.String(name))),
+		sdktrace.WithBatcher(exporter),
+	)
+
+	lock.Lock()
+	defer lock.Unlock()
+	provider = tracerProvider
+	file = closer
+	serviceName = name
+	otel.SetTracerProvider(tracerProvider)
+	log.Log.Info().Str(log.Exporter, conf.Exporter).Msg("enabled tracing")
+	return nil
+}
+
+// Shutdown flushes buffered spans and stops the exporter.
+func Shutdown(ctx context.Context) error {
+	lock.Lock()
+	defer lock.Unlock()
+	if provider == nil {
+		return nil
+	}
+	err := provider.Shutdown(ctx)
+	if file != nil {
+		file.Close()
+	}
+	provider = nil
+	file = nil
+	return err
+}
+
+// HTTPHandler is middleware tracing REST API requests, named after the route template, continuing
+// the trace of the W3C traceparent header when set; it must be used on a router.
+func HTTPHandler(next http.Handler) http.Handler {
+	lock.Lock()
+	service := serviceName
+	lock.Unlock()
+	return otelmux.Middleware(service)(logHandler(next))
+}
+
+// logHandler adds the trace and span IDs of the request to its hlog logger, and so the access log.
+func logHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		spanContext := trace.SpanContextFromContext(request.Context())
+		if spanContext.IsValid() {
+			hlog.FromRequest(request).UpdateContext(func(c zerolog.Context) zerolog.Context {
+				return c.Str(log.TraceID, spanContext.TraceID.String()).
+					Str(log.SpanID, spanContext.SpanID.String())
+			})
+		}
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// UnaryServerInterceptor traces GRPC unary requests, continuing the trace of the traceparent metadata.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return otelgrpc.UnaryServerInterceptor()
+}
+
+// StreamServerInterceptor traces GRPC stream requests, continuing the trace of the traceparent metadata.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return otelgrpc.StreamServerInterceptor()
+}
+
+// StartQuery starts a span for a database query of the said store operation which must be ended by
+// the caller, e.g. defer span.End().
+func StartQuery(ctx context.Context, operation, statement string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, "db "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgres,
+			semconv.DBOperationKey.String(operation),
+			semconv.DBStatementKey.String(statement),
+		))
+}
+
+// RecordError records the error on the current span of the context, flagging the span as failed.
+func RecordError(ctx context.Context, err error) {
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// Logger returns the application logger with the trace and span IDs of the context, if any.
+func Logger(ctx context.Context) *zerolog.Logger {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return &log.Log
+	}
+	logger := log.Log.With().
+		Str(log.TraceID, spanContext.TraceID.String()).
+		Str(log.SpanID, spanContext.SpanID.String()).
+		Logger()
+	return &logger
+}
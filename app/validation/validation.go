@@ -0,0 +1,226 @@
+// Package validation provides structured field level validation of resources.
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// RuleRequired is the name of the required rule.
+	RuleRequired = "required"
+
+	// RuleMinLength is the name of the minimum length rule.
+	RuleMinLength = "min_length"
+
+	// RuleMaxLength is the name of the maximum length rule.
+	RuleMaxLength = "max_length"
+
+	// RuleRange is the name of the numeric range rule.
+	RuleRange = "range"
+
+	// RuleOneOf is the name of the vocabulary rule.
+	RuleOneOf = "one_of"
+
+	// RuleFormat is the name of the pattern rule.
+	RuleFormat = "format"
+
+	// RuleAllowed is the name of the rule used for unknown or disallowed fields.
+	RuleAllowed = "allowed"
+)
+
+// FirstModelYear is the model year of the first automobile.
+const FirstModelYear = 1886
+
+// Violation describes a single validation rule a field failed.
+type Violation struct {
+	// Field is the path of the invalid field.
+	Field string
+
+	// Rule is the name of the rule that failed.
+	Rule string
+
+	// Message describes the violation.
+	Message string
+}
+
+// Errors is a collection of violations that implements error.
+type Errors []Violation
+
+// Error returns the violation messages.
+func (errs Errors) Error() string {
+	messages := make([]string, len(errs))
+	for i, violation := range errs {
+		messages[i] = violation.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// NewError creates Errors with a single violation.
+func NewError(field, rule, message string) Errors {
+	return Errors{{Field: field, Rule: rule, Message: message}}
+}
+
+// Rule validates a field value.
+type Rule struct {
+	// Name of the rule reported in violations.
+	Name string
+
+	// Check returns a message describing why the value of the named field is invalid, or an
+	// empty string if it's valid.
+	Check func(field string, value interface{}) string
+}
+
+// Validator collects the violations of the fields it validates.
+type Validator struct {
+	errs Errors
+}
+
+// New creates a new Validator.
+func New() *Validator {
+	return &Validator{}
+}
+
+// Field validates the value of the field at the said path against the rules. When the value is
+// empty only the required rule is checked.
+func (v *Validator) Field(path string, value interface{}, rules ...Rule) *Validator {
+	empty := isZero(value)
+	for _, rule := range rules {
+		if empty && rule.Name != RuleRequired {
+			continue
+		}
+		if message := rule.Check(path, value); message != "" {
+			v.errs = append(v.errs, Violation{Field: path, Rule: rule.Name, Message: message})
+			if rule.Name == RuleRequired {
+				break
+			}
+		}
+	}
+	return v
+}
+
+// Err returns the collected violations as Errors or nil if there are none.
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// Required checks the value isn't the zero value of its type.
+func Required() Rule {
+	return Rule{
+		Name: RuleRequired,
+		Check: func(field string, value interface{}) string {
+			if isZero(value) {
+				return fmt.Sprintf("%s is required", field)
+			}
+			return ""
+		},
+	}
+}
+
+// MinLength checks a string value has at least min characters.
+func MinLength(min int) Rule {
+	return Rule{
+		Name: RuleMinLength,
+		Check: func(field string, value interface{}) string {
+			if s, ok := value.(string); ok && utf8.RuneCountInString(s) < min {
+				return fmt.Sprintf("%s must be at least %d characters", field, min)
+			}
+			return ""
+		},
+	}
+}
+
+// MaxLength checks a string value has at most max characters.
+func MaxLength(max int) Rule {
+	return Rule{
+		Name: RuleMaxLength,
+		Check: func(field string, value interface{}) string {
+			if s, ok := value.(string); ok && utf8.RuneCountInString(s) > max {
+				return fmt.Sprintf("%s must be at most %d characters", field, max)
+			}
+			return ""
+		},
+	}
+}
+
+// Range checks an integer value is between min and max inclusive.
+func Range(min, max int64) Rule {
+	return Rule{
+		Name: RuleRange,
+		Check: func(field string, value interface{}) string {
+			rv := reflect.ValueOf(value)
+			switch rv.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				if n := rv.Int(); n >= min && n <= max {
+					return ""
+				}
+			default:
+				return fmt.Sprintf("%s must be an integer", field)
+			}
+			return fmt.Sprintf("%s must be between %d and %d", field, min, max)
+		},
+	}
+}
+
+// ModelYear checks an integer value is a model year between the first automobile and next year.
+func ModelYear() Rule {
+	return Range(FirstModelYear, int64(NextModelYear()))
+}
+
+// NextModelYear returns the latest model year that can currently be sold.
+func NextModelYear() int {
+	return time.Now().Year() + 1
+}
+
+// OneOf checks a string value is one of the vocabulary values ignoring case. An empty vocabulary
+// allows any value.
+func OneOf(vocabulary ...string) Rule {
+	return Rule{
+		Name: RuleOneOf,
+		Check: func(field string, value interface{}) string {
+			s, ok := value.(string)
+			if !ok || len(vocabulary) == 0 {
+				return ""
+			}
+			for _, allowed := range vocabulary {
+				if strings.EqualFold(s, allowed) {
+					return ""
+				}
+			}
+			return fmt.Sprintf("%s must be one of: %s", field, strings.Join(vocabulary, ", "))
+		},
+	}
+}
+
+// Format checks a string value matches the pattern, described by the said format in violations.
+func Format(pattern *regexp.Regexp, format string) Rule {
+	return Rule{
+		Name: RuleFormat,
+		Check: func(field string, value interface{}) string {
+			if s, ok := value.(string); ok && !pattern.MatchString(s) {
+				return fmt.Sprintf("%s must be %s", field, format)
+			}
+			return ""
+		},
+	}
+}
+
+// isZero returns if the value is nil or the zero value of its type.
+func isZero(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return rv.IsNil() || (rv.Kind() != reflect.Ptr && rv.Kind() != reflect.Interface && rv.Len() == 0)
+	}
+	return rv.IsZero()
+}
@@ -1,9 +1,13 @@
+import cbor2
+import grpc
+import msgpack
 import os
 import random
 import requests
 import time
 import unittest
 import uuid
+import yaml
 
 
 ACCEPT_JSON = "application/json"
@@ -13,6 +17,69 @@ def get_env(key, default=None):
     return os.getenv(key) or default
 
 
+ERROR_FORMAT = get_env("HTTP_ERROR_FORMAT", "problem")
+# the admin endpoints are only enabled, with authentication, when the admin key is set
+ADMIN_KEY = get_env("ADMIN_KEY")
+# the key of API requests, created by setUpModule when authentication is enabled
+API_KEY = None
+TEST_TENANT = "app-test"
+# the tenant header of unauthenticated requests is ignored unless trusted
+TRUST_TENANT_HEADER = get_env("TENANT_TRUST_HEADER", "false") == "true"
+
+
+def server_url():
+    return 'http://' + get_env("API_HOSTNAME", "172.22.0.3") + \
+        ":" + get_env("API_PORT", "8080")
+
+
+def create_api_key(name, **kwargs):
+    body = {"name": name, "tenant_id": TEST_TENANT,
+            "roles": ["inventory_admin"]}
+    body.update(kwargs)
+    return requests.post(server_url() + "/admin/api-keys", timeout=4,
+                         headers={"x-api-key": ADMIN_KEY}, json=body)
+
+
+def setUpModule():
+    global API_KEY
+    if not ADMIN_KEY:
+        return
+    for i in range(10):
+        try:
+            resp = create_api_key("app_test")
+            break
+        except requests.exceptions.ConnectionError:
+            time.sleep(2)
+    resp.raise_for_status()
+    API_KEY = resp.json()["key"]
+
+
+def tenant_headers(tenant):
+    # authenticated requests get the tenant of their key rather than the requested tenant
+    if not API_KEY:
+        return {"x-tenant-id": tenant}
+    resp = create_api_key(tenant, tenant_id=tenant)
+    resp.raise_for_status()
+    return {"x-api-key": resp.json()["key"]}
+
+
+def proto_string(field, value):
+    # encodes a string field of a protobuf message, so no generated stubs are needed
+    data = value.encode()
+    return bytes([field << 3 | 2, len(data)]) + data
+
+
+def grpc_call(method, request=b"", api_key=None, stream=False):
+    target = get_env("API_HOSTNAME", "172.22.0.3") + \
+        ":" + get_env("GRPC_PORT", "10010")
+    metadata = [("x-api-key", api_key)] if api_key else None
+    with grpc.insecure_channel(target) as channel:
+        if stream:
+            call = channel.unary_stream(method)
+            return list(call(request, timeout=4, metadata=metadata))
+        return channel.unary_unary(method)(request, timeout=4, metadata=metadata)
+
+
 def generate_vehicles(make, model, year, int_color, ext_color, count):
     vehicles = []
     for i in range(count):
@@ -41,6 +108,8 @@ class RequestContext(object):
         self.headers = headers or {'Accept': ACCEPT_JSON}
         if 'Accept' not in self.headers:
             self.headers['Accept'] = ACCEPT_JSON
+        if API_KEY and 'x-api-key' not in self.headers:
+            self.headers['x-api-key'] = API_KEY
 
         self.timeout = timeout
         # TODO: retries
@@ -83,6 +152,8 @@ class BaseClient(object):
         return responses
 
     def post(self, url, body, request_context=None, **kwargs):
+        if 'data' in kwargs:
+            return self._request(url, 'post', request_context=request_context, **kwargs)
         return self._request(url, 'post', request_context=request_context, json=body, **kwargs)
 
     def put(self, url, body, request_context=None, **kwargs):
@@ -169,14 +240,18 @@ class TestVehicleCrud(unittest.TestCase):
         self.assertEqual(resp.status_code, 200)
         created = resp.json()
         self.assert_vehicle_equal(vehicle, created)
+        self.assertEqual(1, created.get("version"))
+        self.assertEqual(created.get("created_at"), created.get("updated_at"))
 
         vehicle["year"] = 2021
         vehicle["interior_color"] = "Black"
         vehicle["exterior_color"] = "Green"
         resp = self.client.update(vehicle["vin"], vehicle)
         self.assertEqual(resp.status_code, 200)
-        created = resp.json()
-        self.assert_vehicle_equal(vehicle, created)
+        updated = resp.json()
+        self.assert_vehicle_equal(vehicle, updated)
+        self.assertEqual(2, updated.get("version"))
+        self.assertEqual(created.get("created_at"), updated.get("created_at"))
 
         resp = self.client.get(vehicle["vin"])
         self.assertEqual(resp.status_code, 200)
@@ -215,6 +290,35 @@ class TestVehicleCrud(unittest.TestCase):
         self.assertEqual(resp.status_code, 200)
         self.assertEqual(5, len(resp.json()))
 
+    def test_search_injection(self):
+        vehicles = generate_vehicles(
+            "Mazda", "CX5", 2021, "Black", "Grey", 2)
+        for v in vehicles:
+            resp = self.client.create(v)
+            self.assertEqual(resp.status_code, 200)
+
+        # values are bound as parameters so they're only ever compared
+        for value in ["Grey' OR '1'='1",
+                      "Grey'; DELETE FROM vehicles; --",
+                      "Grey') UNION SELECT * FROM api_keys; --"]:
+            resp = self.client.list(request_context=None, params={
+                                    "exterior_color": value})
+            self.assertEqual(resp.status_code, 200)
+            self.assertEqual(0, len(resp.json()))
+
+        resp = self.client.list(request_context=None, params={
+                                "year": "2021 OR 1=1"})
+        self.assertEqual(resp.status_code, 400)
+
+        resp = self.client.list(request_context=None, params={
+                                "make IS NOT NULL OR make": "Mazda"})
+        self.assertEqual(resp.status_code, 400)
+
+        resp = self.client.list(request_context=None, params={
+                                "exterior_color": "Grey", "model": "CX5"})
+        self.assertEqual(resp.status_code, 200)
+        self.assertEqual(2, len(resp.json()))
+
     def test_etags(self):
         vehicle = generate_vehicles("Ford", "F150", 2020, "White", "Tan", 1)[0]
         resp = self.client.create(vehicle)
@@ -239,6 +343,7 @@ class TestVehicleCrud(unittest.TestCase):
         self.assertEqual(resp.status_code, 200)
         updated = resp.json()
         self.assert_vehicle_equal(vehicle, updated)
+        self.assertNotEqual(etag, resp.headers.get("ETag"))
         etag = resp.headers.get("ETag")
 
         resp = self.client.delete(vehicle["vin"], request_context=None, headers={
@@ -248,6 +353,375 @@ class TestVehicleCrud(unittest.TestCase):
             "If-None-Match": etag})
         self.assertEqual(resp.status_code, 204)
 
+    def test_tenants(self):
+        vehicle = generate_vehicles("Ford", "F150", 2020, "White", "Tan", 1)[0]
+        if not API_KEY and not TRUST_TENANT_HEADER:
+            # callers can't pick a tenant, the untrusted header is ignored
+            resp = self.client.create(vehicle, request_context=None,
+                                      headers={"x-tenant-id": "dealer-a"})
+            self.assertEqual(resp.status_code, 200)
+            resp = self.client.get(vehicle["vin"])
+            self.assertEqual(resp.status_code, 200)
+            resp = self.client.delete(vehicle["vin"], request_context=None,
+                                      headers={"x-tenant-id": "dealer-b"})
+            self.assertEqual(resp.status_code, 204)
+            return
+
+        tenant_a = tenant_headers("dealer-a")
+        tenant_b = tenant_headers("dealer-b")
+        resp = self.client.create(vehicle, request_context=None, headers=tenant_a)
+        self.assertEqual(resp.status_code, 200)
+
+        resp = self.client.get(vehicle["vin"], request_context=None, headers=tenant_b)
+        self.assertEqual(resp.status_code, 404)
+        resp = self.client.get(vehicle["vin"])
+        self.assertEqual(resp.status_code, 404)
+        resp = self.client.delete(vehicle["vin"], request_context=None, headers=tenant_b)
+        self.assertEqual(resp.status_code, 404)
+
+        resp = self.client.create(vehicle, request_context=None, headers=tenant_b)
+        self.assertEqual(resp.status_code, 200)
+        resp = self.client.list(request_context=None, headers=tenant_a)
+        self.assertEqual(resp.status_code, 200)
+        self.assertEqual(1, len(resp.json()))
+
+        for tenant in [tenant_a, tenant_b]:
+            resp = self.client.delete(vehicle["vin"], request_context=None, headers=tenant)
+            self.assertEqual(resp.status_code, 204)
+
+        resp = self.client.list(request_context=None, headers={"x-tenant-id": "bad tenant"})
+        self.assertEqual(resp.status_code, 403 if API_KEY else 400)
+
+        if API_KEY:
+            # authenticated requests are bound to the tenant of their key
+            resp = self.client.list(request_context=None, headers={"x-tenant-id": "dealer-a"})
+            self.assertEqual(resp.status_code, 403)
+
+    def test_content_negotiation(self):
+        resp = self.client.list(request_context=None, headers={
+            "Accept": "text/html"})
+        self.assertEqual(resp.status_code, 406)
+        self.assertIn("Accept", resp.headers.get("Vary"))
+        if ERROR_FORMAT != "legacy":
+            self.assertEqual(resp.json()["status"], 406)
+
+        # problem details are only used for errors
+        resp = self.client.list(request_context=None, headers={
+            "Accept": "application/problem+json"})
+        self.assertEqual(resp.status_code, 406)
+
+        resp = self.client.list(request_context=None, headers={
+            "Accept": "application/json;q=0.5, application/xml;q=0.9"})
+        self.assertEqual(resp.status_code, 200)
+        self.assertEqual(resp.headers.get("Content-Type"), "application/xml")
+
+        resp = self.client.list(request_context=None, headers={
+            "Accept": "text/*, */*;q=0.1"})
+        self.assertEqual(resp.status_code, 200)
+        self.assertEqual(resp.headers.get("Content-Type"), ACCEPT_JSON)
+
+        vehicle = generate_vehicles("Ford", "F150", 2020, "White", "Tan", 1)[0]
+        resp = self.client.create(vehicle, request_context=None, headers={
+            "Content-Type": "application/json; charset=utf-8"})
+        self.assertEqual(resp.status_code, 200)
+        self.assert_vehicle_equal(vehicle, resp.json())
+
+    def test_encodings(self):
+        encodings = {
+            "application/yaml": (yaml.safe_dump, yaml.safe_load),
+            "application/msgpack": (msgpack.packb, msgpack.unpackb),
+            "application/cbor": (cbor2.dumps, cbor2.loads),
+        }
+        for content_type, (dumps, loads) in encodings.items():
+            vehicle = generate_vehicles(
+                "Ford", "F150", 2020, "White", "Tan", 1)[0]
+            headers = {"Content-Type": content_type, "Accept": content_type}
+            resp = self.client.create(None, request_context=None,
+                                      data=dumps(vehicle), headers=headers)
+            self.assertEqual(resp.status_code, 200)
+            self.assertEqual(resp.headers.get("Content-Type"), content_type)
+            self.assert_vehicle_equal(vehicle, loads(resp.content))
+
+            resp = self.client.get(vehicle["vin"], request_context=None,
+                                   headers=headers)
+            self.assertEqual(resp.status_code, 200)
+            self.assert_vehicle_equal(vehicle, loads(resp.content))
+
+            # lists are bare arrays, as in JSON
+            resp = self.client.list(request_context=None, headers=headers)
+            self.assertEqual(resp.status_code, 200)
+            self.assertEqual(resp.headers.get("Content-Type"), content_type)
+            vehicles = loads(resp.content)
+            self.assertIsInstance(vehicles, list)
+            self.assertEqual(1, len(vehicles))
+            self.assert_vehicle_equal(vehicle, vehicles[0])
+
+            resp = self.client.get(str(uuid.uuid4()), request_context=None,
+                                   headers=headers)
+            self.assertEqual(resp.status_code, 404)
+            self.assertEqual(resp.headers.get("Content-Type"), content_type)
+            error = loads(resp.content)
+            if ERROR_FORMAT == "legacy":
+                self.assertIn("doesn't exist", error["error_message"])
+            else:
+                self.assertEqual(error["status"], 404)
+                self.assertEqual(error["title"], "Not Found")
+
+            resp = self.client.delete(vehicle["vin"])
+            self.assertEqual(resp.status_code, 204)
+
+    def test_problem_details(self):
+        resp = self.client.get(str(uuid.uuid4()))
+        self.assertEqual(resp.status_code, 404)
+        self.assertEqual(resp.headers.get("Content-Type"),
+                         "application/problem+json")
+        problem = resp.json()
+        self.assertEqual(problem["status"], 404)
+        self.assertEqual(problem["title"], "Not Found")
+        self.assertIsNotNone(problem.get("request_id"))
+
+        vehicle = generate_vehicles("Ford", "F150", 2020, "White", "Tan", 1)[0]
+        del vehicle["make"]
+        resp = self.client.create(vehicle)
+        self.assertEqual(resp.status_code, 400)
+        problem = resp.json()
+        self.assertEqual(problem["status"], 400)
+        self.assertEqual(problem["errors"][0]["field"], "make")
+
+    def test_health(self):
+        base_url = 'http://' + get_env("API_HOSTNAME", "172.22.0.3") + \
+            ":" + get_env("API_PORT", "8080")
+        resp = requests.get(base_url + "/healthz", timeout=4)
+        self.assertEqual(resp.status_code, 200)
+        self.assertEqual(resp.json()["status"], "ok")
+
+        resp = requests.get(base_url + "/readyz", timeout=4)
+        self.assertEqual(resp.status_code, 200)
+        ready = resp.json()
+        self.assertEqual(ready["status"], "ok")
+        self.assertEqual(ready["checks"]["database"], "ok")
+        self.assertEqual(ready["checks"]["schema"], "ok")
+
+    def test_metrics(self):
+        self.client.list()
+        base_url = 'http://' + get_env("API_HOSTNAME", "172.22.0.3") + \
+            ":" + get_env("API_PORT", "8080")
+        resp = requests.get(base_url + "/metrics", timeout=4)
+        self.assertEqual(resp.status_code, 200)
+        self.assertIn('vehicle_api_http_requests_total{method="GET",'
+                      'route="/api/vehicles",status="200"}', resp.text)
+        self.assertIn('vehicle_api_db_query_duration_seconds_count'
+                      '{operation="list"}', resp.text)
+        self.assertIn('vehicle_api_db_open_connections', resp.text)
+
+    @unittest.skipUnless(ADMIN_KEY, "admin endpoints not enabled")
+    def test_log_level(self):
+        base_url = 'http://' + get_env("API_HOSTNAME", "172.22.0.3") + \
+            ":" + get_env("API_PORT", "8080")
+        resp = requests.get(base_url + "/admin/log-level", timeout=4)
+        self.assertEqual(resp.status_code, 401)
+
+        headers = {"x-api-key": ADMIN_KEY}
+        resp = requests.get(base_url + "/admin/log-level", timeout=4,
+                            headers=headers)
+        self.assertEqual(resp.status_code, 200)
+        level = resp.json()["level"]
+
+        resp = requests.put(base_url + "/admin/log-level", timeout=4,
+                            headers=headers,
+                            json={"packages": {"resources": "warn"}})
+        self.assertEqual(resp.status_code, 200)
+        self.assertEqual(resp.json()["level"], level)
+        self.assertEqual(resp.json()["packages"]["resources"], "warn")
+
+        resp = requests.put(base_url + "/admin/log-level", timeout=4,
+                            headers=headers, json={"level": "bogus"})
+        self.assertEqual(resp.status_code, 400)
+
+        resp = requests.put(base_url + "/admin/log-level", timeout=4,
+                            headers=headers,
+                            json={"packages": {"resources": ""}})
+        self.assertEqual(resp.status_code, 200)
+        self.assertNotIn("packages", resp.json())
+
+
+@unittest.skipUnless(ADMIN_KEY, "authentication not enabled")
+class TestAuth(unittest.TestCase):
+
+    def setUp(self):
+        super().setUp()
+        self.client = VehicleClient()
+        self.admin_headers = {"x-api-key": ADMIN_KEY}
+
+    def _create_vehicle(self):
+        vehicle = generate_vehicles("Kia", "Soul", 2020, "Grey", "Blue", 1)[0]
+        resp = self.client.create(vehicle)
+        self.assertEqual(resp.status_code, 200)
+        return vehicle
+
+    def _revoke(self, key_id):
+        return requests.delete(server_url() + "/admin/api-keys/" + key_id,
+                               timeout=4, headers=self.admin_headers)
+
+    def test_missing_key(self):
+        resp = self.client.list(headers={"x-api-key": ""})
+        self.assertEqual(resp.status_code, 401)
+        self.assertIn("Bearer", resp.headers.get("WWW-Authenticate"))
+
+        resp = self.client.list(headers={"x-api-key": "bogus"})
+        self.assertEqual(resp.status_code, 401)
+
+    def test_admin_requires_admin_key(self):
+        resp = requests.get(server_url() + "/admin/api-keys", timeout=4)
+        self.assertEqual(resp.status_code, 401)
+
+        resp = requests.get(server_url() + "/admin/api-keys", timeout=4,
+                            headers={"x-api-key": API_KEY})
+        self.assertEqual(resp.status_code, 401)
+
+    def test_key_lifecycle(self):
+        resp = create_api_key("lifecycle")
+        self.assertEqual(resp.status_code, 201)
+        created = resp.json()
+        key = created["key"]
+        self.assertTrue(key.startswith(created["prefix"]))
+        self.assertEqual(created["tenant_id"], TEST_TENANT)
+
+        resp = self.client.list(headers={"x-api-key": key})
+        self.assertEqual(resp.status_code, 200)
+
+        resp = requests.get(server_url() + "/admin/api-keys", timeout=4,
+                            headers=self.admin_headers)
+        self.assertEqual(resp.status_code, 200)
+        listed = [k for k in resp.json() if k["id"] == created["id"]]
+        self.assertEqual(1, len(listed))
+        self.assertNotIn("key", listed[0])
+        self.assertNotIn("revoked_at", listed[0])
+
+        resp = self._revoke(created["id"])
+        self.assertEqual(resp.status_code, 204)
+
+        resp = self.client.list(headers={"x-api-key": key})
+        self.assertEqual(resp.status_code, 401)
+
+        resp = requests.get(server_url() + "/admin/api-keys", timeout=4,
+                            headers=self.admin_headers)
+        listed = [k for k in resp.json() if k["id"] == created["id"]]
+        self.assertIn("revoked_at", listed[0])
+
+        resp = self._revoke("bogus")
+        self.assertEqual(resp.status_code, 404)
+
+    def test_invalid_key_requests(self):
+        resp = create_api_key("")
+        self.assertEqual(resp.status_code, 400)
+
+        resp = create_api_key("expires", expires_in=-1)
+        self.assertEqual(resp.status_code, 400)
+
+        # keys must be bound to a tenant so they can't use the default tenant
+        resp = create_api_key("untenanted", tenant_id="")
+        self.assertEqual(resp.status_code, 400)
+        self.assertEqual(resp.json()["errors"][0]["field"], "tenant_id")
+
+    def test_expired_key(self):
+        resp = create_api_key("expired", expires_in=1)
+        self.assertEqual(resp.status_code, 201)
+        key = resp.json()["key"]
+        self.assertIn("expires_at", resp.json())
+
+        time.sleep(2)
+        resp = self.client.list(headers={"x-api-key": key})
+        self.assertEqual(resp.status_code, 401)
+
+    def test_key_header_precedence(self):
+        resp = self.client.list(headers={"Authorization": "Bearer " + API_KEY})
+        self.assertEqual(resp.status_code, 200)
+
+        resp = self.client.list(headers={"Authorization": "Bearer bogus"})
+        self.assertEqual(resp.status_code, 200)
+
+        resp = self.client.list(headers={"x-api-key": "bogus",
+                                         "Authorization": "Bearer " + API_KEY})
+        self.assertEqual(resp.status_code, 401)
+
+    def test_grpc(self):
+        vehicle = self._create_vehicle()
+        request = proto_string(1, vehicle["vin"])
+
+        with self.assertRaises(grpc.RpcError) as ctx:
+            grpc_call("/vehicle.VehicleStore/GetVehicle", request)
+        self.assertEqual(ctx.exception.code(), grpc.StatusCode.UNAUTHENTICATED)
+
+        with self.assertRaises(grpc.RpcError) as ctx:
+            grpc_call("/vehicle.VehicleStore/ListVehicles", api_key="bogus",
+                      stream=True)
+        self.assertEqual(ctx.exception.code(), grpc.StatusCode.UNAUTHENTICATED)
+
+        self.assertTrue(grpc_call("/vehicle.VehicleStore/GetVehicle", request,
+                                  api_key=API_KEY))
+        self.assertTrue(grpc_call("/vehicle.v2.VehicleStore/GetVehicle",
+                                  request, api_key=API_KEY))
+        self.assertTrue(grpc_call("/vehicle.VehicleStore/ListVehicles",
+                                  api_key=API_KEY, stream=True))
+
+        resp = create_api_key("grpc")
+        self.assertEqual(resp.status_code, 201)
+        key = resp.json()["key"]
+        self.assertTrue(grpc_call("/vehicle.VehicleStore/GetVehicle", request,
+                                  api_key=key))
+        self._revoke(resp.json()["id"])
+        with self.assertRaises(grpc.RpcError) as ctx:
+            grpc_call("/vehicle.VehicleStore/GetVehicle", request, api_key=key)
+        self.assertEqual(ctx.exception.code(), grpc.StatusCode.UNAUTHENTICATED)
+
+        with self.assertRaises(grpc.RpcError) as ctx:
+            grpc_call("/admin.LogAdmin/GetLogLevel", api_key=API_KEY)
+        self.assertEqual(ctx.exception.code(), grpc.StatusCode.UNAUTHENTICATED)
+        self.assertTrue(grpc_call("/admin.LogAdmin/GetLogLevel",
+                                  api_key=ADMIN_KEY))
+
+    def test_read_only_role(self):
+        vehicle = self._create_vehicle()
+        request = proto_string(1, vehicle["vin"])
+        other = generate_vehicles("Kia", "Soul", 2021, "Grey", "Blue", 1)[0]
+
+        # keys without roles get the reader default role
+        for roles in [["READER"], []]:
+            resp = create_api_key("reader", roles=roles)
+            self.assertEqual(resp.status_code, 201)
+            headers = {"x-api-key": resp.json()["key"]}
+
+            resp = self.client.get(vehicle["vin"], headers=headers)
+            self.assertEqual(resp.status_code, 200)
+            resp = self.client.list(headers=headers)
+            self.assertEqual(resp.status_code, 200)
+
+            resp = self.client.create(other, headers=headers)
+            self.assertEqual(resp.status_code, 403)
+            self.assertEqual(resp.json()["status"], 403)
+            resp = self.client.delete(vehicle["vin"], headers=headers)
+            self.assertEqual(resp.status_code, 403)
+
+            key = headers["x-api-key"]
+            self.assertTrue(grpc_call("/vehicle.VehicleStore/GetVehicle",
+                                      request, api_key=key))
+            for method in ["/vehicle.VehicleStore/CreateVehicle",
+                           "/vehicle.VehicleStore/DeleteVehicle",
+                           "/vehicle.v2.VehicleStore/CreateVehicle",
+                           "/vehicle.v2.VehicleStore/DeleteVehicle"]:
+                with self.assertRaises(grpc.RpcError) as ctx:
+                    grpc_call(method, proto_string(1, other["vin"]), api_key=key)
+                self.assertEqual(ctx.exception.code(),
+                                 grpc.StatusCode.PERMISSION_DENIED)
+
+        resp = self.client.get(other["vin"])
+        self.assertEqual(resp.status_code, 404)
+        resp = self.client.get(vehicle["vin"])
+        self.assertEqual(resp.status_code, 200)
+
+        resp = create_api_key("unknown", roles=["owner"])
+        self.assertEqual(resp.status_code, 400)
 
 if __name__ == '__main__':
     unittest.main()